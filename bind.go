@@ -0,0 +1,223 @@
+package pageflow
+
+import (
+	"fmt"
+	"github.com/lefalya/item"
+	"reflect"
+	"strconv"
+)
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// fieldBinding derives the params of a KeyTemplate from the fields of an item.
+// The indexes are resolved once, when the binding is made.
+type fieldBinding struct {
+	template *KeyTemplate
+	indexes  [][]int
+}
+
+func newFieldBinding[T item.Blueprint](template *KeyTemplate, keyFormat string) (*fieldBinding, error) {
+	if template == nil {
+		return nil, fmt.Errorf("%w: key format %q has no KeyTemplate", ErrInvalidParam, keyFormat)
+	}
+
+	var zero T
+	itemType := reflect.TypeOf(zero)
+	if itemType != nil && itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	if itemType == nil || itemType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: fields can only be bound on struct items", ErrInvalidParam)
+	}
+
+	binding := &fieldBinding{template: template}
+	for _, name := range template.names {
+		field, ok := itemType.FieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: placeholder {%s} is not a field of %s", ErrInvalidParam, name, itemType.Name())
+		}
+		if !field.IsExported() {
+			return nil, fmt.Errorf("%w: field %s of %s is unexported", ErrInvalidParam, name, itemType.Name())
+		}
+		if !isParamType(field.Type) {
+			return nil, fmt.Errorf("%w: field %s of type %s cannot be a key param", ErrInvalidParam, name, field.Type)
+		}
+		binding.indexes = append(binding.indexes, field.Index)
+	}
+
+	return binding, nil
+}
+
+func isParamType(fieldType reflect.Type) bool {
+	if fieldType.Implements(stringerType) {
+		return true
+	}
+
+	switch fieldType.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// params reads the bound fields of item, in placeholder order.
+func (fb *fieldBinding) params(item interface{}) ([]string, error) {
+	value := reflect.ValueOf(item)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, fmt.Errorf("%w: cannot derive params from a nil item", ErrInvalidParam)
+		}
+		value = value.Elem()
+	}
+
+	params := make([]string, len(fb.indexes))
+	for i, index := range fb.indexes {
+		field, err := value.FieldByIndexErr(index)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %s: %v", ErrInvalidParam, fb.template.names[i], err)
+		}
+		if (field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) && field.IsNil() {
+			return nil, fmt.Errorf("%w: field %s is nil", ErrInvalidParam, fb.template.names[i])
+		}
+		params[i] = formatParam(field)
+	}
+	return params, nil
+}
+
+func formatParam(field reflect.Value) string {
+	if stringer, ok := field.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10)
+	}
+	return field.String()
+}
+
+// bindParams returns the params item belongs to. Without a binding these are
+// the given ones. With one they are derived from item, and given params that
+// disagree with them are rejected, so a call site cannot file an item under a
+// key the seeder would never use.
+func bindParams(binding *fieldBinding, item interface{}, param []string) ([]string, error) {
+	if binding == nil {
+		return param, nil
+	}
+
+	derived, err := binding.params(item)
+	if err != nil {
+		return nil, err
+	}
+	if param != nil && !equalParams(param, derived) {
+		return nil, fmt.Errorf("%w: item belongs to %v, not %v", ErrInvalidParam, derived, param)
+	}
+	return derived, nil
+}
+
+func equalParams(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// BindFields makes the placeholders of the KeyTemplate given to
+// SetKeyTemplate name fields of T, e.g. MustKeyTemplate("posts:{AuthorID}:{Status}"). IngestItem, AddItem and RemoveItem then derive
+// their params from the item whenever none are given, and reject given ones
+// the item does not belong to.
+func (cr *Paginate[T]) BindFields() error {
+	binding, err := newFieldBinding[T](cr.sortedSetClient.keyTemplate, cr.sortedSetClient.sortedSetKeyFormat)
+	if err != nil {
+		return err
+	}
+	cr.binding = binding
+	return nil
+}
+
+// ParamsOf returns the params item belongs to under the bound fields, or nil
+// when no fields are bound.
+func (cr *Paginate[T]) ParamsOf(item T) ([]string, error) {
+	if cr.binding == nil {
+		return nil, nil
+	}
+	return cr.binding.params(item)
+}
+
+// MoveItem files item again after an update. When its bound fields changed it
+// leaves the sorted set previous belonged to; either way it is then added to
+// its current one, so a changed score takes effect too.
+func (cr *Paginate[T]) MoveItem(previous T, item T) error {
+	if cr.binding == nil {
+		return fmt.Errorf("%w: MoveItem requires bound fields", ErrInvalidParam)
+	}
+
+	from, err := cr.binding.params(previous)
+	if err != nil {
+		return err
+	}
+	to, err := cr.binding.params(item)
+	if err != nil {
+		return err
+	}
+
+	if !equalParams(from, to) {
+		err = cr.RemoveItem(previous, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	return cr.AddItem(item, to)
+}
+
+func (srtd *Sorted[T]) BindFields() error {
+	binding, err := newFieldBinding[T](srtd.sortedSetClient.keyTemplate, srtd.sortedSetClient.sortedSetKeyFormat)
+	if err != nil {
+		return err
+	}
+	srtd.binding = binding
+	return nil
+}
+
+func (srtd *Sorted[T]) ParamsOf(item T) ([]string, error) {
+	if srtd.binding == nil {
+		return nil, nil
+	}
+	return srtd.binding.params(item)
+}
+
+func (srtd *Sorted[T]) MoveItem(previous T, item T) error {
+	if srtd.binding == nil {
+		return fmt.Errorf("%w: MoveItem requires bound fields", ErrInvalidParam)
+	}
+
+	from, err := srtd.binding.params(previous)
+	if err != nil {
+		return err
+	}
+	to, err := srtd.binding.params(item)
+	if err != nil {
+		return err
+	}
+
+	if !equalParams(from, to) {
+		err = srtd.RemoveItem(previous, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	return srtd.IngestItem(item, to, false)
+}
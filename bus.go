@@ -0,0 +1,184 @@
+package pageflow
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lefalya/item"
+	"io"
+	"sync"
+)
+
+// EventType tells subscribers of the invalidation bus what changed.
+type EventType string
+
+const (
+	EventItemUpdated      EventType = "item-updated"
+	EventItemDeleted      EventType = "item-deleted"
+	EventPaginationPurged EventType = "pagination-purged"
+)
+
+// InvalidationEvent is one message on INVALIDATION_CHANNEL. Item events carry
+// the item key format of the Base, the item key and its RandId. Purge events
+// carry the sorted set key format, the sorted set key and its params.
+type InvalidationEvent struct {
+	Type      EventType `json:"type"`
+	KeyFormat string    `json:"keyFormat,omitempty"`
+	Key       string    `json:"key"`
+	RandId    string    `json:"randId,omitempty"`
+	Param     []string  `json:"param,omitempty"`
+}
+
+// decodeEvent reads a message of INVALIDATION_CHANNEL. Messages that are not
+// events are bare keys published by older versions and count as updates.
+func decodeEvent(message string) InvalidationEvent {
+	var event InvalidationEvent
+	err := json.Unmarshal([]byte(message), &event)
+	if err != nil || event.Type == "" {
+		return InvalidationEvent{Type: EventItemUpdated, Key: message}
+	}
+	return event
+}
+
+// InvalidationBus fans the events of INVALIDATION_CHANNEL out to handlers in
+// this process. It holds a single subscription on the store while it has at
+// least one handler. Delivery is Redis pub/sub: at most once, and events
+// published while a process is disconnected are lost, so local state should
+// still expire on its own.
+type InvalidationBus struct {
+	store        Store
+	mutex        sync.Mutex
+	handlers     map[int64]func(event InvalidationEvent)
+	nextHandler  int64
+	subscription io.Closer
+}
+
+// NewInvalidationBus returns a bus over store. Bases created on the same store
+// can share it through SetInvalidationBus, so they share one subscription.
+func NewInvalidationBus(store Store) *InvalidationBus {
+	return &InvalidationBus{
+		store:    store,
+		handlers: make(map[int64]func(event InvalidationEvent)),
+	}
+}
+
+// Publish sends event to every process subscribed to the bus, this one
+// included.
+func (bus *InvalidationBus) Publish(ctx context.Context, event InvalidationEvent) error {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return bus.store.Publish(ctx, INVALIDATION_CHANNEL, string(message))
+}
+
+// Subscribe calls handler for every event until the returned Closer is
+// closed. Handlers run on the delivering goroutine and must not block.
+func (bus *InvalidationBus) Subscribe(handler func(event InvalidationEvent)) (io.Closer, error) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.subscription == nil {
+		subscription, err := bus.store.Subscribe(context.TODO(), INVALIDATION_CHANNEL, bus.dispatch)
+		if err != nil {
+			return nil, err
+		}
+		bus.subscription = subscription
+	}
+
+	bus.nextHandler++
+	bus.handlers[bus.nextHandler] = handler
+	return &busSubscription{bus: bus, id: bus.nextHandler}, nil
+}
+
+func (bus *InvalidationBus) dispatch(message string) {
+	event := decodeEvent(message)
+
+	bus.mutex.Lock()
+	handlers := make([]func(event InvalidationEvent), 0, len(bus.handlers))
+	for _, handler := range bus.handlers {
+		handlers = append(handlers, handler)
+	}
+	bus.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+type busSubscription struct {
+	bus  *InvalidationBus
+	id   int64
+	once sync.Once
+}
+
+// Close removes the handler, and the store subscription with the last one.
+func (sub *busSubscription) Close() error {
+	var err error
+	sub.once.Do(func() {
+		bus := sub.bus
+		bus.mutex.Lock()
+		defer bus.mutex.Unlock()
+
+		delete(bus.handlers, sub.id)
+		if len(bus.handlers) == 0 && bus.subscription != nil {
+			err = bus.subscription.Close()
+			bus.subscription = nil
+		}
+	})
+	return err
+}
+
+// SetInvalidationBus makes this Base publish and listen through bus instead of
+// a bus of its own. Call it before EnableCache or OnInvalidation.
+func (cr *Base[T]) SetInvalidationBus(bus *InvalidationBus) {
+	cr.bus = bus
+}
+
+// OnInvalidation calls handler for the item events of this Base published by
+// any process, e.g. to drop memoized state derived from an item.
+func (cr *Base[T]) OnInvalidation(handler func(event InvalidationEvent)) (io.Closer, error) {
+	return cr.bus.Subscribe(func(event InvalidationEvent) {
+		if event.KeyFormat == cr.itemKeyFormat {
+			handler(event)
+		}
+	})
+}
+
+// OnInvalidation calls handler for the events of the items and the sorted
+// sets of this pagination published by any process, e.g. to drop memoized
+// cursors or segments when a pagination is purged.
+func (cr *Paginate[T]) OnInvalidation(handler func(event InvalidationEvent)) (io.Closer, error) {
+	return cr.baseClient.bus.Subscribe(func(event InvalidationEvent) {
+		if event.KeyFormat == cr.baseClient.itemKeyFormat || event.KeyFormat == cr.sortedSetClient.sortedSetKeyFormat {
+			handler(event)
+		}
+	})
+}
+
+func (srtd *Sorted[T]) OnInvalidation(handler func(event InvalidationEvent)) (io.Closer, error) {
+	return srtd.baseClient.bus.Subscribe(func(event InvalidationEvent) {
+		if event.KeyFormat == srtd.baseClient.itemKeyFormat || event.KeyFormat == srtd.sortedSetClient.sortedSetKeyFormat {
+			handler(event)
+		}
+	})
+}
+
+// publishPurge announces a purged sorted set when the Base of its items
+// publishes invalidations.
+func publishPurge[T item.Blueprint](base *Base[T], sortedSet *SortedSet[T], param []string) error {
+	if !base.publishInvalidations.Load() {
+		return nil
+	}
+
+	key, err := sortedSet.key(param)
+	if err != nil {
+		return err
+	}
+
+	return base.bus.Publish(context.TODO(), InvalidationEvent{
+		Type:      EventPaginationPurged,
+		KeyFormat: sortedSet.sortedSetKeyFormat,
+		Key:       key,
+		Param:     param,
+	})
+}
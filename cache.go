@@ -0,0 +1,262 @@
+package pageflow
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// INVALIDATION_CHANNEL carries an InvalidationEvent for every item written or
+// deleted, and every pagination purged, through a Base with invalidation
+// publishing enabled.
+const INVALIDATION_CHANNEL = "pageflow:invalidate"
+
+// CacheStats counts lookups served by the in-process cache of a Base.
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+}
+
+// localCache is a bounded LRU of encoded items keyed by their Redis key.
+// Entries are kept encoded so callers never share a decoded item, which
+// processors are free to mutate. generation counts the removals, so a value
+// read from the store is not written back over an invalidation that landed
+// while it was being read.
+type localCache struct {
+	mutex      sync.Mutex
+	capacity   int
+	ttl        time.Duration
+	clock      func() time.Time
+	entries    map[string]*list.Element
+	order      *list.List
+	generation uint64
+	hits       atomic.Int64
+	misses     atomic.Int64
+	evictions  atomic.Int64
+
+	subscription io.Closer
+}
+
+type cacheEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+func newLocalCache(capacity int, ttl time.Duration) *localCache {
+	return &localCache{
+		capacity: capacity,
+		ttl:      ttl,
+		clock:    time.Now,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (lc *localCache) get(key string) ([]byte, bool) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	element, ok := lc.entries[key]
+	if !ok {
+		lc.misses.Add(1)
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !lc.clock().Before(entry.expireAt) {
+		lc.order.Remove(element)
+		delete(lc.entries, key)
+		lc.misses.Add(1)
+		return nil, false
+	}
+
+	lc.order.MoveToFront(element)
+	lc.hits.Add(1)
+	return entry.value, true
+}
+
+// set stores value unless the cache was invalidated since generation.
+func (lc *localCache) set(key string, value []byte, generation uint64) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	if lc.generation != generation {
+		return
+	}
+
+	expireAt := lc.clock().Add(lc.ttl)
+	if element, ok := lc.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.value = value
+		entry.expireAt = expireAt
+		lc.order.MoveToFront(element)
+		return
+	}
+
+	lc.entries[key] = lc.order.PushFront(&cacheEntry{key: key, value: value, expireAt: expireAt})
+
+	for lc.order.Len() > lc.capacity {
+		oldest := lc.order.Back()
+		lc.order.Remove(oldest)
+		delete(lc.entries, oldest.Value.(*cacheEntry).key)
+		lc.evictions.Add(1)
+	}
+}
+
+func (lc *localCache) remove(key string) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	lc.generation++
+	if element, ok := lc.entries[key]; ok {
+		lc.order.Remove(element)
+		delete(lc.entries, key)
+	}
+}
+
+func (lc *localCache) currentGeneration() uint64 {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	return lc.generation
+}
+
+func (lc *localCache) stats() CacheStats {
+	return CacheStats{
+		Hits:      lc.hits.Load(),
+		Misses:    lc.misses.Load(),
+		Evictions: lc.evictions.Load(),
+	}
+}
+
+// EnableCache keeps up to capacity recently read items in process for ttl.
+// Every Set, Del and field update on this Base is published on the
+// invalidation bus, and items updated or deleted there by any process are
+// dropped from the cache. Processes that write items without reading them
+// through a cache should call SetPublishInvalidations so readers learn about
+// their writes; otherwise stale entries live until ttl elapses.
+func (cr *Base[T]) EnableCache(capacity int, ttl time.Duration) error {
+	if capacity <= 0 || ttl <= 0 {
+		return fmt.Errorf("%w: cache capacity and ttl must be positive", ErrInvalidParam)
+	}
+
+	cache := newLocalCache(capacity, ttl)
+	subscription, err := cr.bus.Subscribe(func(event InvalidationEvent) {
+		if event.Type != EventPaginationPurged {
+			cache.remove(event.Key)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	cache.subscription = subscription
+
+	cr.publishInvalidations.Store(true)
+	if previous := cr.cache.Swap(cache); previous != nil {
+		return previous.subscription.Close()
+	}
+	return nil
+}
+
+// DisableCache drops the cache and stops listening for invalidations.
+func (cr *Base[T]) DisableCache() error {
+	cache := cr.cache.Swap(nil)
+	if cache == nil {
+		return nil
+	}
+	return cache.subscription.Close()
+}
+
+// SetPublishInvalidations makes Set, Del and field updates publish an item
+// event, and PurgePagination and PurgeSorted a purge event, on the
+// invalidation bus. EnableCache turns it on.
+func (cr *Base[T]) SetPublishInvalidations(enabled bool) {
+	cr.publishInvalidations.Store(enabled)
+}
+
+// CacheStats returns the hit, miss and eviction counters of the cache, or
+// zeroes when it is disabled.
+func (cr *Base[T]) CacheStats() CacheStats {
+	cache := cr.cache.Load()
+	if cache == nil {
+		return CacheStats{}
+	}
+	return cache.stats()
+}
+
+func (cr *Base[T]) cached(key string) (T, bool) {
+	var item T
+	cache := cr.cache.Load()
+	if cache == nil {
+		return item, false
+	}
+
+	value, ok := cache.get(key)
+	cr.GetMetrics().CacheLookup(cr.itemKeyFormat, ok)
+	if !ok {
+		return item, false
+	}
+
+	err := json.Unmarshal(value, &item)
+	if err != nil {
+		cache.remove(key)
+		return item, false
+	}
+	return item, true
+}
+
+// cacheRead is taken before reading items from the store and writes them back
+// to the cache it was taken from, unless that cache was invalidated meanwhile.
+type cacheRead struct {
+	cache      *localCache
+	generation uint64
+}
+
+func (cr *Base[T]) startCacheRead() cacheRead {
+	cache := cr.cache.Load()
+	if cache == nil {
+		return cacheRead{}
+	}
+	return cacheRead{cache: cache, generation: cache.currentGeneration()}
+}
+
+func (read cacheRead) remember(key string, item interface{}) {
+	if read.cache == nil {
+		return
+	}
+
+	value, err := json.Marshal(item)
+	if err != nil {
+		return
+	}
+	read.cache.set(key, value, read.generation)
+}
+
+// uncache drops key from the local cache, if any.
+func (cr *Base[T]) uncache(key string) {
+	if cache := cr.cache.Load(); cache != nil {
+		cache.remove(key)
+	}
+}
+
+// invalidate drops key from the local cache and tells the other processes to
+// do the same.
+func (cr *Base[T]) invalidate(eventType EventType, randId string, key string) error {
+	cr.uncache(key)
+
+	if !cr.publishInvalidations.Load() {
+		return nil
+	}
+	return cr.bus.Publish(context.TODO(), InvalidationEvent{
+		Type:      eventType,
+		KeyFormat: cr.itemKeyFormat,
+		Key:       key,
+		RandId:    randId,
+	})
+}
@@ -0,0 +1,100 @@
+package pageflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CompareAndSet writes item only if the stored item is still at version, the
+// UpdatedAt of the copy the caller read with Get. It stamps item with a newer
+// UpdatedAt, so of two writers that read the same copy only the first
+// succeeds; the other gets ErrConflict and should Get the item again and
+// retry. A zero version expects the item to be missing, and a missing item
+// with any other version fails with ErrNotFound.
+func (cr *Base[T]) CompareAndSet(item T, version time.Time) error {
+	key, errKey := cr.itemKey(item.GetRandId())
+	if errKey != nil {
+		return errKey
+	}
+
+	previousStamp := item.GetUpdatedAt()
+	stamp := time.Now()
+	if !stamp.After(version) {
+		stamp = version.Add(time.Nanosecond)
+	}
+	item.SetUpdatedAt(stamp)
+
+	matches := func(stored T, exists bool) error {
+		if !exists {
+			if version.IsZero() {
+				return nil
+			}
+			return fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		if !stored.GetUpdatedAt().Equal(version) {
+			return fmt.Errorf("%w: %s is at version %s, not %s", ErrConflict, key,
+				stored.GetUpdatedAt().Format(time.RFC3339Nano), version.Format(time.RFC3339Nano))
+		}
+		return nil
+	}
+
+	err := cr.compareAndSet(context.TODO(), key, item, matches)
+	if err != nil {
+		item.SetUpdatedAt(previousStamp)
+		// a conflict means the cached copy is stale, so the retry must read
+		// through to the store
+		cr.uncache(key)
+		return err
+	}
+
+	return cr.invalidate(EventItemUpdated, item.GetRandId(), key)
+}
+
+// compareAndSet writes item to key when matches accepts the stored item,
+// applying zwrites in the same transaction.
+func (cr *Base[T]) compareAndSet(ctx context.Context, key string, item T, matches func(stored T, exists bool) error, zwrites ...ZWrite) error {
+	if cr.storage == StorageHash {
+		values, err := encodeHash(item)
+		if err != nil {
+			return err
+		}
+
+		return cr.store.HCheckAndReplace(ctx, key, func(current map[string]string) error {
+			if len(current) == 0 {
+				var nilItem T
+				return matches(nilItem, false)
+			}
+			stored, err := decodeHash[T](current)
+			if err != nil {
+				return err
+			}
+			return matches(stored, true)
+		}, values, INDIVIDUAL_KEY_TTL, zwrites...)
+	}
+
+	itemInByte, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	err = cr.store.CheckAndSet(ctx, key, func(current string, exists bool) error {
+		var stored T
+		if exists {
+			errorUnmarshal := json.Unmarshal([]byte(current), &stored)
+			if errorUnmarshal != nil {
+				return errorUnmarshal
+			}
+		}
+		return matches(stored, exists)
+	}, string(itemInByte), INDIVIDUAL_KEY_TTL, zwrites...)
+	if err != nil {
+		return err
+	}
+
+	if len(cr.summaryFields) > 0 {
+		return cr.setSummary(key, itemInByte)
+	}
+	return nil
+}
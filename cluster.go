@@ -0,0 +1,171 @@
+package pageflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CLUSTER_SLOTS is the number of hash slots of a Redis Cluster.
+const CLUSTER_SLOTS = 16384
+
+var errCrossSlot = errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+
+// tagMark stands in for the braces of a hash tag while a key is rendered, so
+// braces can neither come from nor be escaped out of a param.
+const tagMark = "\x00"
+
+// KeySlot returns the Redis Cluster hash slot of key. Like Redis it hashes
+// only the content of the first non-empty {hash tag} when there is one.
+func KeySlot(key string) int {
+	return int(crc16(hashedPart(key)) % CLUSTER_SLOTS)
+}
+
+// hashedPart returns the part of key Redis Cluster hashes.
+func hashedPart(key string) string {
+	if open := strings.IndexByte(key, '{'); open >= 0 {
+		if close := strings.IndexByte(key[open+1:], '}'); close > 0 {
+			return key[open+1 : open+1+close]
+		}
+	}
+	return key
+}
+
+// slotTag returns a hash tag, braces included, that puts any key containing
+// it in the slot of key. It returns "" when no tag can, which only happens to
+// keys with stray braces.
+func slotTag(key string) string {
+	hashed := hashedPart(key)
+	if hashed == "" || strings.Contains(hashed, "}") {
+		return ""
+	}
+	return "{" + hashed + "}"
+}
+
+// crc16 is the CRC16-CCITT (XMODEM) checksum Redis Cluster uses.
+func crc16(data string) uint16 {
+	var crc uint16
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// groupBySlot returns the indexes of keys grouped by hash slot, in the order
+// each slot is first seen.
+func groupBySlot(keys []string) [][]int {
+	var groups [][]int
+	bySlot := make(map[int]int)
+	for i, key := range keys {
+		slot := KeySlot(key)
+		group, ok := bySlot[slot]
+		if !ok {
+			group = len(groups)
+			bySlot[slot] = group
+			groups = append(groups, nil)
+		}
+		groups[group] = append(groups[group], i)
+	}
+	return groups
+}
+
+// backendKeys returns keys as store sends them to the backend, which is what
+// their slots must be computed from.
+func backendKeys(store Store, keys []string) []string {
+	resolved := make([]string, len(keys))
+	for i, key := range keys {
+		resolved[i] = store.BackendKey(key)
+	}
+	return resolved
+}
+
+// sameSlot reports whether every key hashes to the same slot.
+func sameSlot(keys ...string) bool {
+	for _, key := range keys[1:] {
+		if KeySlot(key) != KeySlot(keys[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hashTaggedKey renders keyFormat like joinParam, then wraps everything from
+// the first param to the last in a hash tag: "posts:%s:%s" with alice and
+// draft gives "posts:{alice:draft}". Without params the whole key is the tag.
+// Keys derived by suffixing, such as the page markers, keep the tag and land
+// in the same slot.
+func hashTaggedKey(kt *KeyTemplate, keyFormat string, param []string) (string, error) {
+	for _, value := range param {
+		if strings.ContainsAny(value, tagMark+"{}") {
+			return "", fmt.Errorf("%w: param %q cannot be part of a hash tag", ErrInvalidParam, value)
+		}
+	}
+
+	if len(param) == 0 {
+		key, err := joinParam(kt, keyFormat, param)
+		if err != nil {
+			return "", err
+		}
+		return "{" + key + "}", nil
+	}
+
+	marked := append([]string(nil), param...)
+	marked[0] = tagMark + marked[0]
+	marked[len(marked)-1] = marked[len(marked)-1] + tagMark
+
+	key, err := joinParam(kt, keyFormat, marked)
+	if err != nil {
+		return "", err
+	}
+	if strings.Count(key, tagMark) != 2 {
+		return "", fmt.Errorf("%w: key format %q cannot be hash tagged", ErrInvalidParam, keyFormat)
+	}
+
+	key = strings.Replace(key, tagMark, "{", 1)
+	return strings.Replace(key, tagMark, "}", 1), nil
+}
+
+// key builds the sorted set key for param, hash tagged when enabled.
+func (cr *SortedSet[T]) key(param []string) (string, error) {
+	if cr.hashTags {
+		return hashTaggedKey(cr.keyTemplate, cr.sortedSetKeyFormat, param)
+	}
+	return joinParam(cr.keyTemplate, cr.sortedSetKeyFormat, param)
+}
+
+// SetHashTags wraps the params of every key of this sorted set in a Redis
+// Cluster hash tag, so the set and its :firstpage, :lastpage and :blankpage
+// markers share one slot and can be used together in a script or
+// transaction. Changing it on existing data orphans the untagged keys.
+func (cr *SortedSet[T]) SetHashTags(enabled bool) {
+	cr.hashTags = enabled
+}
+
+func (cr *Paginate[T]) SetHashTags(enabled bool) {
+	cr.sortedSetClient.SetHashTags(enabled)
+}
+
+func (srtd *Sorted[T]) SetHashTags(enabled bool) {
+	srtd.sortedSetClient.SetHashTags(enabled)
+}
+
+func (lb *Leaderboard[T]) SetHashTags(enabled bool) {
+	lb.sortedSetClient.SetHashTags(enabled)
+}
+
+// Keys returns the sorted set key for param followed by its first page, last
+// page and blank page marker keys.
+func (cr *Paginate[T]) Keys(param []string) ([]string, error) {
+	key, err := cr.sortedSetClient.key(param)
+	if err != nil {
+		return nil, err
+	}
+	return []string{key, key + ":firstpage", key + ":lastpage", key + ":blankpage"}, nil
+}
@@ -0,0 +1,339 @@
+package pageflow
+
+import (
+	"context"
+	"github.com/lefalya/item"
+	"regexp"
+	"strings"
+)
+
+// ConsistencyReport is what Verify found, and Repair fixed, under one key
+// prefix. Item keys and sorted sets expire independently and Base.Del leaves
+// sorted sets alone, so sorted sets collect members whose item is gone and
+// Fetch returns short pages.
+type ConsistencyReport struct {
+	// SortedSets is the number of sorted sets checked.
+	SortedSets int64
+	// DanglingMembers maps a sorted set key to its members whose item key no
+	// longer exists.
+	DanglingMembers map[string][]string
+	// OrphanedMarkers are :firstpage, :lastpage and :fresh markers whose
+	// sorted set no longer exists.
+	OrphanedMarkers []string
+	// MissingTTL are sorted sets and markers that never expire.
+	MissingTTL []string
+}
+
+// Consistent reports whether nothing was found.
+func (report *ConsistencyReport) Consistent() bool {
+	return len(report.DanglingMembers) == 0 && len(report.OrphanedMarkers) == 0 && len(report.MissingTTL) == 0
+}
+
+// paramMark stands in for every param when a key format is turned into a
+// pattern. It differs from tagMark, so it survives hash tagging.
+const paramMark = "\x01"
+
+// markerSuffixes are the keys written next to a sorted set. Only the first
+// three mean nothing without their set: a blank page marker stands for a set
+// that is empty on purpose, and locks and tombstones outlive the set.
+var markerSuffixes = []string{":firstpage", ":lastpage", ":fresh", ":blankpage", ":revalidating"}
+
+const orphanableMarkers = 3
+
+// markerOf splits a marker key into its sorted set key and suffix.
+func markerOf(key string) (string, string, bool) {
+	if index := strings.LastIndex(key, ":tombstone:"); index >= 0 {
+		return key[:index], ":tombstone:", true
+	}
+	for _, suffix := range markerSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return strings.TrimSuffix(key, suffix), suffix, true
+		}
+	}
+	return "", "", false
+}
+
+// keyPattern returns the literal prefix every sorted set key of this format
+// starts with, and a pattern matching those keys whatever their params. Each
+// param matches one key segment only, so the keys of a longer format sharing
+// the prefix, like "posts:%s:drafts" next to "posts:%s", are left out.
+func (cr *SortedSet[T]) keyPattern() (string, *regexp.Regexp, error) {
+	count := formatVerbs(cr.sortedSetKeyFormat)
+	if kt := cr.keyTemplate; kt != nil {
+		count = len(kt.names)
+	}
+
+	param := make([]string, count)
+	for i := range param {
+		param[i] = paramMark
+	}
+	key, err := cr.key(param)
+	if err != nil {
+		return "", nil, err
+	}
+
+	prefix, _, _ := strings.Cut(key, paramMark)
+	pattern := strings.ReplaceAll(regexp.QuoteMeta(key), paramMark, "[^:]+")
+	return prefix, regexp.MustCompile("(?s)^" + pattern + "$"), nil
+}
+
+// isWrongType reports whether err is the WRONGTYPE error of a command run
+// against a key holding another kind of value.
+func isWrongType(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
+
+// exist reports, for each item key, whether it is in the store. The cache is
+// bypassed so a cached copy cannot hide an expired key.
+func (cr *Base[T]) exist(ctx context.Context, keys []string) ([]bool, error) {
+	found := make([]bool, len(keys))
+	if cr.storage == StorageHash {
+		results, err := cr.store.HGetAllMany(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		for i, result := range results {
+			found[i] = len(result) > 0
+		}
+		return found, nil
+	}
+
+	values, err := cr.store.MGet(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		found[i] = value != nil
+	}
+	return found, nil
+}
+
+// danglingMembers returns the members of the sorted set key whose item key
+// no longer exists.
+func danglingMembers[T item.Blueprint](ctx context.Context, base *Base[T], sortedSet *SortedSet[T], key string) ([]string, error) {
+	members, err := sortedSet.store.ZRange(ctx, key, 0, -1, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var dangling []string
+	for start := 0; start < len(members); start += SCAN_COUNT {
+		end := start + SCAN_COUNT
+		if end > len(members) {
+			end = len(members)
+		}
+
+		keys := make([]string, end-start)
+		for i, member := range members[start:end] {
+			keys[i], err = base.itemKey(member.Member)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		found, err := base.exist(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		for i, ok := range found {
+			if !ok {
+				dangling = append(dangling, members[start+i].Member)
+			}
+		}
+	}
+	return dangling, nil
+}
+
+// checkConsistency scans the keys starting with prefix and checks the sorted
+// sets of sortedSet and their markers among them. With repair it removes
+// dangling members and clears the :lastpage marker of their set, so the next
+// Fetch past the remaining members reseeds instead of ending early. It
+// deletes orphaned markers and markers without TTL, which are all rebuilt on
+// demand, and gives sorted sets without TTL SORTED_SET_TTL.
+func checkConsistency[T item.Blueprint](ctx context.Context, base *Base[T], sortedSet *SortedSet[T], prefix string, repair bool) (*ConsistencyReport, error) {
+	formatPrefix, pattern, err := sortedSet.keyPattern()
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		prefix = formatPrefix
+	}
+
+	store := sortedSet.store
+	report := &ConsistencyReport{DanglingMembers: make(map[string][]string)}
+	var orphanable []string
+
+	err = store.ScanPrefix(ctx, prefix, func(keys []string) error {
+		var sets []string
+		var markers []string
+		for _, key := range keys {
+			setKey, suffix, isMarker := markerOf(key)
+			switch {
+			case isMarker && pattern.MatchString(setKey):
+				markers = append(markers, key)
+				for _, orphanableSuffix := range markerSuffixes[:orphanableMarkers] {
+					if suffix == orphanableSuffix {
+						orphanable = append(orphanable, key)
+					}
+				}
+			case !isMarker && pattern.MatchString(key):
+				sets = append(sets, key)
+			}
+		}
+
+		// keys of another type that happen to match are not ours to check
+		zsets := sets[:0]
+		for _, key := range sets {
+			dangling, err := danglingMembers(ctx, base, sortedSet, key)
+			if isWrongType(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			zsets = append(zsets, key)
+			if len(dangling) == 0 {
+				continue
+			}
+			report.DanglingMembers[key] = dangling
+
+			if repair {
+				err = store.ZRem(ctx, key, dangling...)
+				if err != nil {
+					return err
+				}
+				err = store.Del(ctx, key+":lastpage")
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		sets = zsets
+		report.SortedSets += int64(len(sets))
+
+		checked := append(sets, markers...)
+		ttls, err := store.TTLMany(ctx, checked)
+		if err != nil {
+			return err
+		}
+
+		var expire []string
+		var drop []string
+		for i, ttl := range ttls {
+			if ttl >= 0 {
+				continue
+			}
+			report.MissingTTL = append(report.MissingTTL, checked[i])
+			if i < len(sets) {
+				expire = append(expire, checked[i])
+			} else {
+				drop = append(drop, checked[i])
+			}
+		}
+
+		if repair {
+			err = store.ExpireMany(ctx, expire, SORTED_SET_TTL)
+			if err != nil {
+				return err
+			}
+			if len(drop) > 0 {
+				return store.Del(ctx, drop...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a marker and its set can come in different batches, so orphans are only
+	// known once the scan is done
+	for start := 0; start < len(orphanable); start += SCAN_COUNT {
+		end := start + SCAN_COUNT
+		if end > len(orphanable) {
+			end = len(orphanable)
+		}
+
+		setKeys := make([]string, end-start)
+		for i, key := range orphanable[start:end] {
+			setKeys[i], _, _ = markerOf(key)
+		}
+		cards, err := store.ZCardMany(ctx, setKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		var orphaned []string
+		for i, card := range cards {
+			if card == 0 {
+				orphaned = append(orphaned, orphanable[start+i])
+			}
+		}
+		report.OrphanedMarkers = append(report.OrphanedMarkers, orphaned...)
+
+		if repair && len(orphaned) > 0 {
+			err = store.Del(ctx, orphaned...)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// Verify scans the keys starting with prefix and reports the dangling
+// members, orphaned markers and keys without TTL of the sorted sets of this
+// Paginate among them. An empty prefix scans every sorted set of its key
+// format; a longer one, e.g. "posts:alice", narrows the scan. Verify changes
+// nothing.
+func (cr *Paginate[T]) Verify(prefix string) (*ConsistencyReport, error) {
+	return cr.VerifyContext(context.Background(), prefix)
+}
+
+// VerifyContext is Verify with its span and store calls under ctx.
+func (cr *Paginate[T]) VerifyContext(ctx context.Context, prefix string) (*ConsistencyReport, error) {
+	ctx, span := cr.StartSpan(ctx, "Paginate.Verify")
+	report, err := checkConsistency(ctx, cr.baseClient, cr.sortedSetClient, prefix, false)
+	EndSpan(span, err)
+	return report, err
+}
+
+// Repair is Verify that also fixes what it reports. Dangling members are
+// removed and the :lastpage marker of their set is cleared, so the seeder
+// refills the set. Orphaned markers and markers without TTL are deleted, as
+// they are rebuilt on demand, and sorted sets without TTL get SORTED_SET_TTL.
+func (cr *Paginate[T]) Repair(prefix string) (*ConsistencyReport, error) {
+	return cr.RepairContext(context.Background(), prefix)
+}
+
+// RepairContext is Repair with its span and store calls under ctx.
+func (cr *Paginate[T]) RepairContext(ctx context.Context, prefix string) (*ConsistencyReport, error) {
+	ctx, span := cr.StartSpan(ctx, "Paginate.Repair")
+	report, err := checkConsistency(ctx, cr.baseClient, cr.sortedSetClient, prefix, true)
+	EndSpan(span, err)
+	return report, err
+}
+
+func (srtd *Sorted[T]) Verify(prefix string) (*ConsistencyReport, error) {
+	return srtd.VerifyContext(context.Background(), prefix)
+}
+
+func (srtd *Sorted[T]) VerifyContext(ctx context.Context, prefix string) (*ConsistencyReport, error) {
+	ctx, span := srtd.StartSpan(ctx, "Sorted.Verify")
+	report, err := checkConsistency(ctx, srtd.baseClient, srtd.sortedSetClient, prefix, false)
+	EndSpan(span, err)
+	return report, err
+}
+
+func (srtd *Sorted[T]) Repair(prefix string) (*ConsistencyReport, error) {
+	return srtd.RepairContext(context.Background(), prefix)
+}
+
+func (srtd *Sorted[T]) RepairContext(ctx context.Context, prefix string) (*ConsistencyReport, error) {
+	ctx, span := srtd.StartSpan(ctx, "Sorted.Repair")
+	report, err := checkConsistency(ctx, srtd.baseClient, srtd.sortedSetClient, prefix, true)
+	EndSpan(span, err)
+	return report, err
+}
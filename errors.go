@@ -0,0 +1,29 @@
+package pageflow
+
+import (
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+)
+
+// Errors returned by pageflow. They are usually wrapped with more context, so
+// compare them with errors.Is.
+var (
+	ErrNotFound             = errors.New("item not found")
+	ErrEmptySortedSet       = errors.New("sorted set is empty")
+	ErrDirectionUnset       = errors.New("must set direction!")
+	ErrInvalidParam         = errors.New("invalid param")
+	ErrUnsupportedSortField = errors.New("unsupported sort field")
+	ErrCursorInvalid        = errors.New("invalid cursor")
+	ErrHashStorageRequired  = errors.New("requires hash storage")
+	ErrConflict             = errors.New("write conflict")
+)
+
+// notFound translates the store's redis.Nil into ErrNotFound for key, so the
+// driver does not leak to callers. Any other error is returned unchanged.
+func notFound(err error, key string) error {
+	if errors.Is(err, redis.Nil) {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return err
+}
@@ -0,0 +1,417 @@
+package pageflow
+
+import (
+	"container/heap"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/lefalya/item"
+	"github.com/redis/go-redis/v9"
+)
+
+// FANIN_SERVER_MERGE_LIMIT is the combined cardinality up to which FanIn lets
+// Redis merge the sources with a single ZUNION. Above it the sources are read
+// in page-sized batches and merged in process.
+const FANIN_SERVER_MERGE_LIMIT = 1000
+
+// fanInMetricsLabel stands in for a key format, since a FanIn reads any
+// number of them at once.
+const fanInMetricsLabel = "fanin"
+
+// fanInPosition is the last member consumed from one source. Everything that
+// sorts at or before it has already been returned.
+type fanInPosition struct {
+	Score  float64 `json:"s"`
+	Member string  `json:"m"`
+}
+
+// FanIn reads a single merged timeline out of many sorted sets, e.g. the
+// per-author sets maintained by Paginate when building a home feed.
+type FanIn[T item.Blueprint] struct {
+	store            Store
+	baseClient       *Base[T]
+	itemPerPage      int64
+	direction        string
+	serverMergeLimit int64
+	metrics          Metrics
+}
+
+func (f *FanIn[T]) GetItemPerPage() int64 {
+	return f.itemPerPage
+}
+
+func (f *FanIn[T]) GetDirection() string {
+	return f.direction
+}
+
+// Fetch returns the next page of the merged timeline. cursor is the value
+// returned by the previous call, or empty for the first page. The returned
+// cursor is empty once every source is exhausted.
+func (f *FanIn[T]) Fetch(sources []QuerySource, cursor string) ([]T, string, error) {
+	if f.direction == "" {
+		return nil, "", ErrDirectionUnset
+	}
+
+	positions, err := decodeFanInCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keys := make([]string, 0, len(sources))
+	seen := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		key, err := source.key()
+		if err != nil {
+			return nil, "", err
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, "", nil
+	}
+
+	total, err := f.totalMembers(keys)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var merged []ZMember
+	var more bool
+	// ZUNION needs every source in one slot on a Redis Cluster
+	if total <= f.serverMergeLimit && sameSlot(backendKeys(f.store, keys)...) {
+		merged, more, err = f.mergeOnServer(keys, positions)
+	} else {
+		merged, more, err = f.mergeOnClient(keys, positions)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	items, err := f.baseClient.GetMany(zMembers(merged))
+	if err != nil {
+		return nil, "", err
+	}
+	for i := len(items); i < len(merged); i++ {
+		metricsOrNoop(f.metrics).DanglingItem(fanInMetricsLabel)
+	}
+
+	if !more {
+		return items, "", nil
+	}
+
+	nextCursor, err := encodeFanInCursor(positions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return items, nextCursor, nil
+}
+
+func (f *FanIn[T]) totalMembers(keys []string) (int64, error) {
+	cards, err := f.store.ZCardMany(context.TODO(), keys)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, card := range cards {
+		total += card
+	}
+	return total, nil
+}
+
+// mergeOnServer unions the sources on the backend and keeps the first page after
+// the cursor. Every source has consumed the merged stream up to the same
+// point, so all positions advance to the last returned member.
+func (f *FanIn[T]) mergeOnServer(keys []string, positions map[string]fanInPosition) ([]ZMember, bool, error) {
+	union, err := f.store.ZUnion(context.TODO(), keys)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if f.direction == Descending {
+		for i, j := 0, len(union)-1; i < j; i, j = i+1, j-1 {
+			union[i], union[j] = union[j], union[i]
+		}
+	}
+
+	// a k-way merge leaves every unread member after the most advanced
+	// position, so that one is enough to skip what was already returned
+	var after *fanInPosition
+	for _, key := range keys {
+		if position, ok := positions[key]; ok {
+			if after == nil || f.before(*after, position) {
+				p := position
+				after = &p
+			}
+		}
+	}
+
+	var merged []ZMember
+	var more bool
+	for _, member := range union {
+		if after != nil && !f.before(*after, zPosition(member)) {
+			continue
+		}
+		if int64(len(merged)) == f.itemPerPage {
+			more = true
+			break
+		}
+		merged = append(merged, member)
+	}
+
+	if len(merged) > 0 {
+		last := zPosition(merged[len(merged)-1])
+		for _, key := range keys {
+			positions[key] = last
+		}
+	}
+
+	return merged, more, nil
+}
+
+// mergeOnClient pulls at most one page from each source, which is enough to
+// know the next page of the merged stream, and k-way merges them through a
+// heap. Only the consumed prefix of each source moves its position forward.
+// If a batch that did not reach the end of its source is drained before the
+// page is complete, the page is cut short there, since that source may hold
+// members that sort earlier than the remaining heads.
+func (f *FanIn[T]) mergeOnClient(keys []string, positions map[string]fanInPosition) ([]ZMember, bool, error) {
+	batches, truncated, err := f.readSources(keys, positions)
+	if err != nil {
+		return nil, false, err
+	}
+
+	h := &fanInHeap{direction: f.direction}
+	for i, key := range keys {
+		if len(batches[i]) > 0 {
+			h.heads = append(h.heads, fanInHead{key: key, batch: batches[i], truncated: truncated[i]})
+		}
+	}
+	heap.Init(h)
+
+	var merged []ZMember
+	emitted := make(map[string]bool)
+	for h.Len() > 0 && int64(len(merged)) < f.itemPerPage {
+		head := &h.heads[0]
+		member := head.batch[head.index]
+		positions[head.key] = zPosition(member)
+
+		if !emitted[member.Member] {
+			emitted[member.Member] = true
+			merged = append(merged, member)
+		}
+
+		head.index++
+		if head.index == len(head.batch) {
+			if head.truncated {
+				f.advance(keys, positions, merged)
+				return merged, true, nil
+			}
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+
+	f.advance(keys, positions, merged)
+	return merged, h.Len() > 0, nil
+}
+
+// advance moves every source that is still behind up to the last returned
+// member. Whatever those sources have left sorts at or after it, and a member
+// sorting exactly there is a copy of the one just returned, so the next page
+// does not return it again.
+func (f *FanIn[T]) advance(keys []string, positions map[string]fanInPosition, merged []ZMember) {
+	if len(merged) == 0 {
+		return
+	}
+
+	last := zPosition(merged[len(merged)-1])
+	for _, key := range keys {
+		if position, ok := positions[key]; !ok || f.before(position, last) {
+			positions[key] = last
+		}
+	}
+}
+
+// readSources fetches up to itemPerPage members after each source's position
+// in one pipeline. Ties on the boundary score are read again by ZRANGE and
+// dropped here by comparing members. truncated reports, per source, whether
+// the read stopped before the end of the set.
+func (f *FanIn[T]) readSources(keys []string, positions map[string]fanInPosition) ([][]ZMember, []bool, error) {
+	batches := make([][]ZMember, len(keys))
+	truncated := make([]bool, len(keys))
+	offsets := make([]int64, len(keys))
+	pending := make([]int, len(keys))
+	for i := range keys {
+		pending[i] = i
+	}
+
+	for len(pending) > 0 {
+		queries := make([]ZRangeQuery, len(pending))
+		for j, i := range pending {
+			queries[j] = f.sourceRange(keys[i], positions, offsets[i])
+		}
+
+		results, err := f.store.ZRangeByScoreMany(context.TODO(), queries)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var next []int
+		for j, i := range pending {
+			members := results[j]
+			position, hasPosition := positions[keys[i]]
+			truncated[i] = int64(len(members)) == f.itemPerPage
+
+			for _, member := range members {
+				if hasPosition && !f.before(position, zPosition(member)) {
+					continue
+				}
+				batches[i] = append(batches[i], member)
+			}
+
+			// a full batch made only of already-consumed ties means there
+			// is more to read past them
+			if len(batches[i]) == 0 && truncated[i] {
+				offsets[i] += f.itemPerPage
+				next = append(next, i)
+			}
+		}
+		pending = next
+	}
+
+	return batches, truncated, nil
+}
+
+func (f *FanIn[T]) sourceRange(key string, positions map[string]fanInPosition, offset int64) ZRangeQuery {
+	query := ZRangeQuery{
+		Key:    key,
+		Min:    NegativeInfinity,
+		Max:    PositiveInfinity,
+		Rev:    f.direction == Descending,
+		Offset: offset,
+		Count:  f.itemPerPage,
+	}
+
+	if position, ok := positions[key]; ok {
+		if f.direction == Descending {
+			query.Max = Inclusive(position.Score)
+		} else {
+			query.Min = Inclusive(position.Score)
+		}
+	}
+
+	return query
+}
+
+// before reports whether a sorts strictly before b in the merged order. Ties
+// on score fall back to the member, matching how Redis orders equal scores.
+func (f *FanIn[T]) before(a fanInPosition, b fanInPosition) bool {
+	return fanInBefore(f.direction, a, b)
+}
+
+func fanInBefore(direction string, a fanInPosition, b fanInPosition) bool {
+	if direction == Descending {
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Member > b.Member
+	}
+
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return a.Member < b.Member
+}
+
+func zPosition(member ZMember) fanInPosition {
+	return fanInPosition{Score: member.Score, Member: member.Member}
+}
+
+type fanInHead struct {
+	key       string
+	batch     []ZMember
+	index     int
+	truncated bool
+}
+
+type fanInHeap struct {
+	direction string
+	heads     []fanInHead
+}
+
+func (h *fanInHeap) Len() int { return len(h.heads) }
+
+func (h *fanInHeap) Less(i, j int) bool {
+	a := h.heads[i].batch[h.heads[i].index]
+	b := h.heads[j].batch[h.heads[j].index]
+	return fanInBefore(h.direction, zPosition(a), zPosition(b))
+}
+
+func (h *fanInHeap) Swap(i, j int) { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+
+func (h *fanInHeap) Push(x interface{}) { h.heads = append(h.heads, x.(fanInHead)) }
+
+func (h *fanInHeap) Pop() interface{} {
+	last := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return last
+}
+
+func encodeFanInCursor(positions map[string]fanInPosition) (string, error) {
+	raw, err := json.Marshal(positions)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeFanInCursor(cursor string) (map[string]fanInPosition, error) {
+	positions := make(map[string]fanInPosition)
+	if cursor == "" {
+		return positions, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCursorInvalid, cursor)
+	}
+
+	err = json.Unmarshal(raw, &positions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCursorInvalid, cursor)
+	}
+
+	return positions, nil
+}
+
+func NewFanIn[T item.Blueprint](client redis.UniversalClient, baseClient *Base[T], itemPerPage int64, direction string) *FanIn[T] {
+	return NewFanInWithStore[T](NewRedisStore(client), baseClient, itemPerPage, direction)
+}
+
+func NewFanInWithStore[T item.Blueprint](store Store, baseClient *Base[T], itemPerPage int64, direction string) *FanIn[T] {
+	if direction != Ascending && direction != Descending {
+		direction = Descending
+	}
+
+	return &FanIn[T]{
+		store:            store,
+		baseClient:       baseClient,
+		itemPerPage:      itemPerPage,
+		direction:        direction,
+		serverMergeLimit: FANIN_SERVER_MERGE_LIMIT,
+	}
+}
+
+// SetServerMergeLimit changes the combined size under which sources are
+// merged by ZUNION. A negative value always merges in process, and so do
+// sources spread over several Redis Cluster slots.
+func (f *FanIn[T]) SetServerMergeLimit(limit int64) {
+	f.serverMergeLimit = limit
+}
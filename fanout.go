@@ -0,0 +1,274 @@
+package pageflow
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	FANOUT_BATCH_SIZE  = 500
+	FANOUT_CONCURRENCY = 4
+)
+
+// fanOutTarget carries what AddItem would read from one sorted set before
+// deciding whether the item belongs in the cached window.
+type fanOutTarget struct {
+	key       string
+	blankPage bool
+	firstPage bool
+	lastPage  bool
+	total     int64
+	boundary  []ZMember
+}
+
+// SetFanOutConcurrency limits how many batches AddItemToMany sends to the store
+// at the same time.
+func (cr *Paginate[T]) SetFanOutConcurrency(concurrency int) {
+	cr.fanOutConcurrency = concurrency
+}
+
+// AddItemToMany adds item to every sorted set in sortedSetParams with the same
+// rules as AddItem, including bound fields and tombstones, but reads and writes each batch of keys with a handful of
+// batched store calls instead of several round trips per key. The returned
+// map holds the error of each key that failed, indexed by sorted set key; it
+// is empty when every key succeeded.
+func (cr *Paginate[T]) AddItemToMany(item T, sortedSetParams [][]string) (map[string]error, error) {
+	if cr.direction == "" {
+		return nil, ErrDirectionUnset
+	}
+
+	score, err := getItemScore(item, cr.sortingReference)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(sortedSetParams))
+	params := make(map[string][]string, len(sortedSetParams))
+	for i, param := range sortedSetParams {
+		param, err = bindParams(cr.binding, item, param)
+		if err != nil {
+			return nil, err
+		}
+		keys[i], err = cr.sortedSetClient.key(param)
+		if err != nil {
+			return nil, err
+		}
+		params[keys[i]] = param
+	}
+
+	concurrency := cr.fanOutConcurrency
+	if concurrency <= 0 {
+		concurrency = FANOUT_CONCURRENCY
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	failed := make(map[string]error)
+	semaphore := make(chan struct{}, concurrency)
+
+	for start := 0; start < len(keys); start += FANOUT_BATCH_SIZE {
+		end := start + FANOUT_BATCH_SIZE
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(batch []string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			batchErrors := cr.fanOutBatch(batch, params, item.GetRandId(), score)
+			if len(batchErrors) == 0 {
+				return
+			}
+
+			mutex.Lock()
+			for key, errBatch := range batchErrors {
+				failed[key] = errBatch
+			}
+			mutex.Unlock()
+		}(keys[start:end])
+	}
+	wg.Wait()
+
+	return failed, nil
+}
+
+func (cr *Paginate[T]) fanOutBatch(keys []string, params map[string][]string, member string, score float64) map[string]error {
+	failed := make(map[string]error)
+
+	targets, err := cr.readFanOutTargets(keys)
+	if err != nil {
+		for _, key := range keys {
+			failed[key] = err
+		}
+		return failed
+	}
+
+	var markerKeys, markerOwners, addKeys []string
+	for _, target := range targets {
+		if target.blankPage {
+			markerKeys = append(markerKeys, target.key+":blankpage")
+			markerOwners = append(markerOwners, target.key)
+		}
+
+		addItem, delFirstPage := cr.fanOutDecision(target, score)
+		if delFirstPage {
+			markerKeys = append(markerKeys, target.key+":firstpage")
+			markerOwners = append(markerOwners, target.key)
+		}
+		if addItem {
+			addKeys = append(addKeys, target.key)
+			// added back explicitly, as AddItem lifts the tombstone
+			if cr.sortedSetClient.tombstoneTTL > 0 {
+				markerKeys = append(markerKeys, setTombstoneKey(target.key, member))
+				markerOwners = append(markerOwners, target.key)
+			}
+		}
+	}
+
+	if len(markerKeys) > 0 {
+		errDel := cr.store.Del(context.TODO(), markerKeys...)
+		if errDel != nil {
+			for _, key := range markerOwners {
+				failed[key] = errDel
+			}
+		}
+	}
+
+	if len(addKeys) == 0 {
+		return failed
+	}
+
+	var trimKeys, addedKeys []string
+	var addedParams [][]string
+	for i, errAdd := range cr.store.ZAddMany(context.TODO(), addKeys, ZMember{Member: member, Score: score}, SORTED_SET_TTL) {
+		if errAdd != nil {
+			failed[addKeys[i]] = errAdd
+			continue
+		}
+		addedKeys = append(addedKeys, addKeys[i])
+		addedParams = append(addedParams, params[addKeys[i]])
+
+		// same trimming as addToSortedSet, decided from the size read
+		// earlier so the whole batch is trimmed in one round trip
+		size := targets[addKeys[i]].total + 1
+		if cr.maxLength > 0 && targets[addKeys[i]].total >= cr.maxLength {
+			trimKeys = append(trimKeys, addKeys[i])
+			size = cr.maxLength
+		}
+		cr.GetMetrics().SortedSetSize(cr.sortedSetClient.sortedSetKeyFormat, size)
+	}
+
+	errTrack := cr.sortedSetClient.trackAll(context.TODO(), addedParams, member)
+	if errTrack != nil {
+		for _, key := range addedKeys {
+			failed[key] = errTrack
+		}
+	}
+
+	if len(trimKeys) == 0 {
+		return failed
+	}
+
+	var trimErrors []error
+	if cr.direction == Ascending {
+		trimErrors = cr.store.ZRemRangeByRankMany(context.TODO(), trimKeys, cr.maxLength, -1)
+	} else {
+		trimErrors = cr.store.ZRemRangeByRankMany(context.TODO(), trimKeys, 0, -cr.maxLength-1)
+	}
+
+	var lastPageKeys, lastPageOwners []string
+	for i, errTrim := range trimErrors {
+		if errTrim != nil {
+			failed[trimKeys[i]] = errTrim
+			continue
+		}
+		lastPageKeys = append(lastPageKeys, trimKeys[i]+":lastpage")
+		lastPageOwners = append(lastPageOwners, trimKeys[i])
+	}
+
+	if len(lastPageKeys) > 0 {
+		errDel := cr.store.Del(context.TODO(), lastPageKeys...)
+		if errDel != nil {
+			for _, key := range lastPageOwners {
+				failed[key] = errDel
+			}
+		}
+	}
+
+	return failed
+}
+
+// readFanOutTargets reads the page markers, size and boundary member of every
+// key in three batched calls.
+func (cr *Paginate[T]) readFanOutTargets(keys []string) (map[string]fanOutTarget, error) {
+	markerKeys := make([]string, 0, len(keys)*3)
+	for _, key := range keys {
+		markerKeys = append(markerKeys, key+":blankpage", key+":firstpage", key+":lastpage")
+	}
+
+	markers, err := cr.store.MGet(context.TODO(), markerKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := cr.store.ZCardMany(context.TODO(), keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var boundaries [][]ZMember
+	if cr.direction == Descending {
+		boundaries, err = cr.store.ZRangeMany(context.TODO(), keys, 0, 0, false)
+	} else {
+		boundaries, err = cr.store.ZRangeMany(context.TODO(), keys, -1, -1, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]fanOutTarget, len(keys))
+	for i, key := range keys {
+		targets[key] = fanOutTarget{
+			key:       key,
+			blankPage: markers[i*3] == "1",
+			firstPage: markers[i*3+1] == "1",
+			lastPage:  markers[i*3+2] == "1",
+			total:     totals[i],
+			boundary:  boundaries[i],
+		}
+	}
+
+	return targets, nil
+}
+
+// fanOutDecision mirrors the boundary checks of IngestItem: an item is only
+// added when it falls inside the window already cached in the sorted set, so
+// the seeder stays responsible for everything past it.
+func (cr *Paginate[T]) fanOutDecision(target fanOutTarget, score float64) (bool, bool) {
+	total := target.total
+	if total == 0 || len(target.boundary) == 0 {
+		return false, false
+	}
+
+	isFirstPage := target.firstPage
+	isLastPage := target.lastPage
+	boundary := target.boundary[0].Score
+
+	if cr.direction == Descending {
+		if score >= boundary {
+			return true, total == cr.itemPerPage && isFirstPage
+		}
+	} else {
+		if score <= boundary {
+			if total == cr.itemPerPage && isFirstPage {
+				return false, true
+			}
+			return isFirstPage || isLastPage, false
+		}
+	}
+
+	return false, false
+}
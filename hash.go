@@ -0,0 +1,326 @@
+package pageflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	StorageJSON = "JSON"
+	StorageHash = "Hash"
+)
+
+var hashFieldCache sync.Map
+
+// UpdateFields overwrites only the given fields of a stored item. Field names
+// are the JSON names of T. Missing items are not created; ErrNotFound is
+// returned instead.
+func (cr *Base[T]) UpdateFields(param string, fields map[string]interface{}) error {
+	if cr.storage != StorageHash {
+		return fmt.Errorf("%w: field updates", ErrHashStorageRequired)
+	}
+
+	types := hashFieldTypes[T]()
+	values := make(map[string]string, len(fields))
+	for name, value := range fields {
+		if _, ok := types[name]; !ok {
+			return fmt.Errorf("%w: unknown field %s", ErrInvalidParam, name)
+		}
+
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		values[name] = encodeHashValue(raw)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	key, errKey := cr.itemKey(param)
+	if errKey != nil {
+		return errKey
+	}
+	err := cr.store.HSetIfExists(context.TODO(), key, values, INDIVIDUAL_KEY_TTL)
+	if err != nil {
+		return notFound(err, key)
+	}
+
+	return cr.invalidate(EventItemUpdated, param, key)
+}
+
+// IncrementField atomically adds delta to an integer field with HINCRBY and
+// returns the new value.
+func (cr *Base[T]) IncrementField(param string, field string, delta int64) (int64, error) {
+	if cr.storage != StorageHash {
+		return 0, fmt.Errorf("%w: field updates", ErrHashStorageRequired)
+	}
+
+	fieldType, ok := hashFieldTypes[T]()[field]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown field %s", ErrInvalidParam, field)
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return 0, fmt.Errorf("%w: field %s is not an integer", ErrInvalidParam, field)
+	}
+
+	key, errKey := cr.itemKey(param)
+	if errKey != nil {
+		return 0, errKey
+	}
+	value, err := cr.store.HIncrByIfExists(context.TODO(), key, field, delta, INDIVIDUAL_KEY_TTL)
+	if err != nil {
+		return 0, notFound(err, key)
+	}
+
+	return value, cr.invalidate(EventItemUpdated, param, key)
+}
+
+// GetFields reads only the named fields with HMGET. Every other field of the
+// returned item is left at its zero value.
+func (cr *Base[T]) GetFields(param string, fields ...string) (T, error) {
+	var nilItem T
+	if cr.storage != StorageHash {
+		return nilItem, fmt.Errorf("%w: field projection", ErrHashStorageRequired)
+	}
+
+	key, err := cr.itemKey(param)
+	if err != nil {
+		return nilItem, err
+	}
+
+	result, err := cr.store.HMGet(context.TODO(), key, fields...)
+	if err != nil {
+		return nilItem, err
+	}
+
+	values := make(map[string]string, len(fields))
+	for i, value := range result {
+		if raw, ok := value.(string); ok {
+			values[fields[i]] = raw
+		}
+	}
+	if len(values) == 0 {
+		return nilItem, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	return decodeHash[T](values)
+}
+
+func (cr *Base[T]) getHash(key string) (T, error) {
+	var nilItem T
+
+	result, err := cr.store.HGetAll(context.TODO(), key)
+	if err != nil {
+		return nilItem, err
+	}
+	if len(result) == 0 {
+		return nilItem, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	item, err := decodeHash[T](result)
+	if err != nil {
+		return nilItem, err
+	}
+
+	errExpire := cr.store.Expire(context.TODO(), key, INDIVIDUAL_KEY_TTL)
+	if errExpire != nil {
+		return nilItem, errExpire
+	}
+
+	return item, nil
+}
+
+func (cr *Base[T]) getManyHash(read cacheRead, found map[string]T, params []string, keys []string) error {
+	results, err := cr.store.HGetAllMany(context.TODO(), keys)
+	if err != nil {
+		return err
+	}
+
+	var touched []string
+	for i, result := range results {
+		if len(result) == 0 {
+			continue
+		}
+
+		item, errDecode := decodeHash[T](result)
+		if errDecode != nil {
+			continue
+		}
+
+		found[params[i]] = item
+		touched = append(touched, keys[i])
+		read.remember(keys[i], item)
+	}
+
+	if len(touched) > 0 {
+		errExpire := cr.store.ExpireMany(context.TODO(), touched, INDIVIDUAL_KEY_TTL)
+		if errExpire != nil {
+			return errExpire
+		}
+	}
+
+	return nil
+}
+
+func (cr *Base[T]) setHash(key string, item T) error {
+	values, err := encodeHash(item)
+	if err != nil {
+		return err
+	}
+
+	// replace rather than merge, so fields that became null do not linger
+	return cr.store.HReplace(context.TODO(), key, values, INDIVIDUAL_KEY_TTL)
+}
+
+// encodeHash flattens an item into hash fields through its JSON form, so the
+// field names and embedded structs follow the same rules as JSON storage.
+// Strings are stored bare and numbers as their decimal text, which keeps
+// HINCRBY usable; nested values stay JSON encoded.
+func encodeHash(item interface{}) (map[string]string, error) {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(raw, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(fields))
+	for name, value := range fields {
+		if string(value) == "null" {
+			continue
+		}
+		values[name] = encodeHashValue(value)
+	}
+
+	return values, nil
+}
+
+func encodeHashValue(raw json.RawMessage) string {
+	if len(raw) > 0 && raw[0] == '"' {
+		var text string
+		if json.Unmarshal(raw, &text) == nil {
+			return text
+		}
+	}
+	return string(raw)
+}
+
+func decodeHash[T any](values map[string]string) (T, error) {
+	var item T
+	types := hashFieldTypes[T]()
+
+	fields := make(map[string]json.RawMessage, len(values))
+	for name, value := range values {
+		fields[name] = decodeHashValue(types[name], value)
+	}
+
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return item, err
+	}
+
+	err = json.Unmarshal(raw, &item)
+	return item, err
+}
+
+// decodeHashValue turns a stored field back into JSON. Plain strings were
+// stored without quotes and get them back; anything else is used as is when
+// it decodes into the field's type and treated as a string otherwise, which
+// covers text-like types such as time.Time.
+func decodeHashValue(fieldType reflect.Type, value string) json.RawMessage {
+	quoted, _ := json.Marshal(value)
+
+	if fieldType != nil {
+		base := fieldType
+		for base.Kind() == reflect.Ptr {
+			base = base.Elem()
+		}
+		if base.Kind() == reflect.String {
+			return quoted
+		}
+
+		if json.Unmarshal([]byte(value), reflect.New(fieldType).Interface()) == nil {
+			return json.RawMessage(value)
+		}
+		return quoted
+	}
+
+	if json.Valid([]byte(value)) {
+		return json.RawMessage(value)
+	}
+	return quoted
+}
+
+// hashFieldTypes maps the JSON field names of T, including those promoted from
+// embedded structs, to their Go types.
+func hashFieldTypes[T any]() map[string]reflect.Type {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if cached, ok := hashFieldCache.Load(t); ok {
+		return cached.(map[string]reflect.Type)
+	}
+
+	fields := make(map[string]reflect.Type)
+	if t.Kind() == reflect.Struct {
+		collectHashFields(t, fields)
+	}
+	hashFieldCache.Store(t, fields)
+
+	return fields
+}
+
+// collectHashFields registers direct fields before promoted ones so that, as
+// in encoding/json, the shallower field wins a name clash.
+func collectHashFields(t reflect.Type, fields map[string]reflect.Type) {
+	var embedded []reflect.Type
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Ptr {
+				embeddedType = embeddedType.Elem()
+			}
+			if embeddedType.Kind() == reflect.Struct {
+				embedded = append(embedded, embeddedType)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = field.Type
+		}
+	}
+
+	for _, embeddedType := range embedded {
+		collectHashFields(embeddedType, fields)
+	}
+}
@@ -0,0 +1,172 @@
+package pageflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keyEscaper percent-encodes the characters that separate or tag key
+// segments. '%' is encoded too, so escaping never maps two params to the same
+// key.
+var keyEscaper = strings.NewReplacer("%", "%25", ":", "%3A", "{", "%7B", "}", "%7D")
+
+// KeyTemplate builds keys from a pattern with named placeholders, e.g.
+// "posts:{author}:{status}". Params fill the placeholders in order and are
+// escaped, so a param containing ":" or "%" cannot produce another valid key.
+//
+// Templates are opt-in per component: SetKeyTemplate on a Base, SortedSet,
+// Paginate or Sorted, or Template on a QuerySource, makes it build its keys
+// from the template. Every keyFormat string keeps its fmt.Sprintf behaviour,
+// braces included, e.g. the static "{feed}:items".
+type KeyTemplate struct {
+	pattern  string
+	literals []string
+	names    []string
+}
+
+// NewKeyTemplate parses pattern. Placeholder names must be non-empty, unique
+// and made of letters, digits and underscores.
+func NewKeyTemplate(pattern string) (*KeyTemplate, error) {
+	kt := &KeyTemplate{pattern: pattern}
+	seen := make(map[string]bool)
+
+	rest := pattern
+	for {
+		open := strings.IndexByte(rest, '{')
+		close := strings.IndexByte(rest, '}')
+		if open < 0 {
+			if close >= 0 {
+				return nil, fmt.Errorf("%w: unbalanced } in key template %q", ErrInvalidParam, pattern)
+			}
+			kt.literals = append(kt.literals, rest)
+			return kt, nil
+		}
+		if close < open {
+			return nil, fmt.Errorf("%w: unbalanced braces in key template %q", ErrInvalidParam, pattern)
+		}
+
+		name := rest[open+1 : close]
+		if !isPlaceholderName(name) {
+			return nil, fmt.Errorf("%w: invalid placeholder {%s} in key template %q", ErrInvalidParam, name, pattern)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicate placeholder {%s} in key template %q", ErrInvalidParam, name, pattern)
+		}
+		seen[name] = true
+
+		kt.literals = append(kt.literals, rest[:open])
+		kt.names = append(kt.names, name)
+		rest = rest[close+1:]
+	}
+}
+
+// MustKeyTemplate is NewKeyTemplate for patterns known at compile time. It
+// panics on an invalid pattern.
+func MustKeyTemplate(pattern string) *KeyTemplate {
+	kt, err := NewKeyTemplate(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return kt
+}
+
+func isPlaceholderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the pattern.
+func (kt *KeyTemplate) String() string {
+	return kt.pattern
+}
+
+// Placeholders returns the placeholder names in the order params fill them.
+func (kt *KeyTemplate) Placeholders() []string {
+	return append([]string(nil), kt.names...)
+}
+
+// Render fills the placeholders with params. It fails with ErrInvalidParam
+// unless exactly one param is given per placeholder.
+func (kt *KeyTemplate) Render(params ...string) (string, error) {
+	if len(params) != len(kt.names) {
+		return "", fmt.Errorf("%w: key template %q takes %d params, got %d", ErrInvalidParam, kt.pattern, len(kt.names), len(params))
+	}
+
+	var key strings.Builder
+	for i := range kt.names {
+		key.WriteString(kt.literals[i])
+		key.WriteString(keyEscaper.Replace(params[i]))
+	}
+	key.WriteString(kt.literals[len(kt.literals)-1])
+	return key.String(), nil
+}
+
+// SetKeyTemplate makes the Base build its item keys from kt instead of the
+// keyFormat it was made with.
+func (cr *Base[T]) SetKeyTemplate(kt *KeyTemplate) {
+	cr.itemKeyFormat = kt.String()
+	cr.keyTemplate = kt
+}
+
+// SetKeyTemplate makes the sorted set build its keys from kt instead of the
+// keyFormat it was made with. Call it before BindFields.
+func (cr *SortedSet[T]) SetKeyTemplate(kt *KeyTemplate) {
+	cr.sortedSetKeyFormat = kt.String()
+	cr.keyTemplate = kt
+}
+
+func (cr *Paginate[T]) SetKeyTemplate(kt *KeyTemplate) {
+	cr.sortedSetClient.SetKeyTemplate(kt)
+}
+
+func (srtd *Sorted[T]) SetKeyTemplate(kt *KeyTemplate) {
+	srtd.sortedSetClient.SetKeyTemplate(kt)
+}
+
+// joinParam builds a key from kt, or from the fmt.Sprintf format keyFormat
+// when kt is nil. A param count that does not match the format fails with
+// ErrInvalidParam instead of producing a "%!s(MISSING)" key.
+func joinParam(kt *KeyTemplate, keyFormat string, param []string) (string, error) {
+	if kt != nil {
+		return kt.Render(param...)
+	}
+
+	if formatVerbs(keyFormat) != len(param) {
+		return "", fmt.Errorf("%w: key format %q does not take %d params", ErrInvalidParam, keyFormat, len(param))
+	}
+
+	interfaces := make([]interface{}, len(param))
+	for i, v := range param {
+		interfaces[i] = v
+	}
+	return fmt.Sprintf(keyFormat, interfaces...), nil
+}
+
+// formatVerbs counts the verbs of the fmt.Sprintf format keyFormat, leaving
+// out the literal "%%".
+func formatVerbs(keyFormat string) int {
+	count := 0
+	for i := 0; i < len(keyFormat); i++ {
+		if keyFormat[i] != '%' {
+			continue
+		}
+		if i+1 < len(keyFormat) && keyFormat[i+1] == '%' {
+			i++
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// itemKey builds the key of one item.
+func (cr *Base[T]) itemKey(randId string) (string, error) {
+	return joinParam(cr.keyTemplate, cr.itemKeyFormat, []string{randId})
+}
@@ -0,0 +1,188 @@
+package pageflow
+
+import (
+	"context"
+	"fmt"
+	"github.com/lefalya/item"
+	"github.com/redis/go-redis/v9"
+	"math"
+	"time"
+)
+
+const (
+	// TieByMember leaves ties to the store, which orders equal scores by member.
+	TieByMember = "TieByMember"
+	// TieByFirstReached ranks whoever reached a score first above those who
+	// reached it later. Scores must then be whole numbers. The time of each
+	// increment shares the precision of a float64 with the points, so the
+	// larger the points the coarser the tie-break: to the millisecond up to
+	// about 500 points, to about a second at a million points and to about
+	// twenty minutes at a billion. Ties closer than that are broken by member.
+	TieByFirstReached = "TieByFirstReached"
+	// tieResolution spreads UnixMilli over [0, 1) so it fits below the
+	// integer part of the score.
+	tieResolution = 1e13
+)
+
+// Standing is one row of a leaderboard. Rank is zero-based, highest first.
+type Standing[T item.Blueprint] struct {
+	Item  T
+	Rank  int64
+	Score float64
+}
+
+// Leaderboard ranks items by a score that changes by increments, highest
+// first, on top of a SortedSet.
+type Leaderboard[T item.Blueprint] struct {
+	store           Store
+	baseClient      *Base[T]
+	sortedSetClient *SortedSet[T]
+	tieBreak        string
+	metrics         Metrics
+}
+
+func (lb *Leaderboard[T]) IncrementScore(param []string, item T, delta float64) (float64, error) {
+	key, errKey := lb.sortedSetClient.key(param)
+	if errKey != nil {
+		return 0, errKey
+	}
+
+	if lb.tieBreak == TieByFirstReached {
+		if delta != math.Trunc(delta) {
+			return 0, fmt.Errorf("%w: increment must be a whole number when ties are broken by time", ErrInvalidParam)
+		}
+
+		return lb.store.ZIncrWithTieBreak(
+			context.TODO(),
+			key,
+			item.GetRandId(),
+			delta,
+			tieFraction(time.Now()),
+			SORTED_SET_TTL,
+		)
+	}
+
+	return lb.store.ZIncrBy(context.TODO(), key, item.GetRandId(), delta, SORTED_SET_TTL)
+}
+
+func (lb *Leaderboard[T]) RankOf(param []string, item T) (int64, error) {
+	key, errKey := lb.sortedSetClient.key(param)
+	if errKey != nil {
+		return 0, errKey
+	}
+
+	rank, err := lb.store.ZRank(context.TODO(), key, item.GetRandId(), true)
+	if err != nil {
+		return 0, notFound(err, item.GetRandId())
+	}
+
+	return rank, nil
+}
+
+func (lb *Leaderboard[T]) ScoreOf(param []string, item T) (float64, error) {
+	key, errKey := lb.sortedSetClient.key(param)
+	if errKey != nil {
+		return 0, errKey
+	}
+
+	score, err := lb.store.ZScore(context.TODO(), key, item.GetRandId())
+	if err != nil {
+		return 0, notFound(err, item.GetRandId())
+	}
+
+	return lb.points(score), nil
+}
+
+func (lb *Leaderboard[T]) TopN(param []string, n int64) ([]Standing[T], error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	return lb.standings(param, 0, n-1)
+}
+
+// AroundMe returns up to n standings above item, item itself, and up to n
+// standings below it.
+func (lb *Leaderboard[T]) AroundMe(param []string, item T, n int64) ([]Standing[T], error) {
+	rank, err := lb.RankOf(param, item)
+	if err != nil {
+		return nil, err
+	}
+
+	start := rank - n
+	if start < 0 {
+		start = 0
+	}
+
+	return lb.standings(param, start, rank+n)
+}
+
+func (lb *Leaderboard[T]) RemoveItem(param []string, item T) error {
+	return lb.sortedSetClient.DeleteFromSortedSet(param, item)
+}
+
+func (lb *Leaderboard[T]) standings(param []string, start int64, stop int64) ([]Standing[T], error) {
+	key, errKey := lb.sortedSetClient.key(param)
+	if errKey != nil {
+		return nil, errKey
+	}
+
+	members, err := lb.store.ZRange(context.TODO(), key, start, stop, true)
+	if err != nil {
+		return nil, err
+	}
+	listRandIds := zMembers(members)
+
+	found, err := lb.baseClient.getManyById(listRandIds)
+	if err != nil {
+		return nil, err
+	}
+
+	standings := make([]Standing[T], 0, len(members))
+	for i, member := range members {
+		item, ok := found[listRandIds[i]]
+		if !ok {
+			lb.GetMetrics().DanglingItem(lb.sortedSetClient.sortedSetKeyFormat)
+			continue
+		}
+
+		standings = append(standings, Standing[T]{
+			Item:  item,
+			Rank:  start + int64(i),
+			Score: lb.points(member.Score),
+		})
+	}
+
+	return standings, nil
+}
+
+// points strips the tie-break fraction from a stored score.
+func (lb *Leaderboard[T]) points(score float64) float64 {
+	if lb.tieBreak == TieByFirstReached {
+		return math.Floor(score)
+	}
+	return score
+}
+
+// tieFraction maps reachedAt into (0, 1), earlier times getting larger
+// fractions. Once added to the points only the bits the points leave free are
+// kept, see TieByFirstReached.
+func tieFraction(reachedAt time.Time) float64 {
+	return 1 - float64(reachedAt.UnixMilli())/tieResolution
+}
+
+func NewLeaderboard[T item.Blueprint](client redis.UniversalClient, baseClient *Base[T], keyFormat string, tieBreak string) *Leaderboard[T] {
+	return NewLeaderboardWithStore[T](NewRedisStore(client), baseClient, keyFormat, tieBreak)
+}
+
+func NewLeaderboardWithStore[T item.Blueprint](store Store, baseClient *Base[T], keyFormat string, tieBreak string) *Leaderboard[T] {
+	if tieBreak != TieByFirstReached {
+		tieBreak = TieByMember
+	}
+
+	return &Leaderboard[T]{
+		store:           store,
+		baseClient:      baseClient,
+		sortedSetClient: NewSortedSetWithStore[T](store, keyFormat),
+		tieBreak:        tieBreak,
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"github.com/lefalya/item"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/trace"
 	"math/rand"
 	"reflect"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,6 +21,7 @@ const (
 	INDIVIDUAL_KEY_TTL = DAY * 7
 	SORTED_SET_TTL     = DAY * 2
 	RANDID_LENGTH      = 16
+	SCAN_COUNT         = 500
 	firstPage          = "FIRST_PAGE"
 	middlePage         = "MIDDLE_PAGE"
 	lastPage           = "LAST_PAGE"
@@ -41,15 +44,6 @@ func RandId() string {
 	return string(result)
 }
 
-func joinParam(keyFormat string, param []string) string {
-	interfaces := make([]interface{}, len(param))
-	for i, v := range param {
-		interfaces[i] = v
-	}
-	sortedSetKey := fmt.Sprintf(keyFormat, interfaces...)
-	return sortedSetKey
-}
-
 type MongoItemBlueprint interface {
 	item.Blueprint
 	SetObjectID()
@@ -121,46 +115,166 @@ func InitSQLItem[T SQLItemBlueprint](sqlItem T) {
 }
 
 type Base[T item.Blueprint] struct {
-	client        redis.UniversalClient
+	store         Store
 	itemKeyFormat string
+	keyTemplate   *KeyTemplate
+	storage       string
+	summaryFields []string
+
+	cache                atomic.Pointer[localCache]
+	publishInvalidations atomic.Bool
+	bus                  *InvalidationBus
+	tombstoneTTL         time.Duration
+	version              func(item T) int64
+	metrics              Metrics
 }
 
 func (cr *Base[T]) Get(param string) (T, error) {
 	var nilItem T
-	key := fmt.Sprintf(cr.itemKeyFormat, param)
+	key, err := cr.itemKey(param)
+	if err != nil {
+		return nilItem, err
+	}
+
+	if item, ok := cr.cached(key); ok {
+		return item, nil
+	}
+	read := cr.startCacheRead()
 
-	result := cr.client.Get(context.TODO(), key)
-	if result.Err() != nil {
-		if result.Err() == redis.Nil {
-			return nilItem, redis.Nil
+	if cr.storage == StorageHash {
+		item, err := cr.getHash(key)
+		if err != nil {
+			return nilItem, err
 		}
-		return nilItem, result.Err()
+		read.remember(key, item)
+		return item, nil
+	}
+
+	value, err := cr.store.Get(context.TODO(), key)
+	if err != nil {
+		return nilItem, notFound(err, key)
 	}
 
 	var item T
-	errorUnmarshal := json.Unmarshal([]byte(result.Val()), &item)
+	errorUnmarshal := json.Unmarshal([]byte(value), &item)
 	if errorUnmarshal != nil {
 		return nilItem, errorUnmarshal
 	}
 
-	setExpire := cr.client.Expire(context.TODO(), key, INDIVIDUAL_KEY_TTL)
-	if setExpire.Err() != nil {
-		return nilItem, setExpire.Err()
+	errExpire := cr.store.ExpireMany(context.TODO(), cr.withSummary(key), INDIVIDUAL_KEY_TTL)
+	if errExpire != nil {
+		return nilItem, errExpire
 	}
 
+	read.remember(key, item)
 	return item, nil
 }
 
+// GetMany hydrates several items with a single MGET instead of one GET per id.
+// Ids whose key is missing or cannot be decoded are skipped, so the result may
+// be shorter than params. The order of params is preserved.
+func (cr *Base[T]) GetMany(params []string) ([]T, error) {
+	found, err := cr.getManyById(params)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]T, 0, len(found))
+	for _, param := range params {
+		if item, ok := found[param]; ok {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (cr *Base[T]) getManyById(params []string) (map[string]T, error) {
+	found := make(map[string]T, len(params))
+	if len(params) == 0 {
+		return found, nil
+	}
+
+	var keys []string
+	var missing []string
+	for _, param := range params {
+		key, errKey := cr.itemKey(param)
+		if errKey != nil {
+			return nil, errKey
+		}
+		if item, ok := cr.cached(key); ok {
+			found[param] = item
+			continue
+		}
+		keys = append(keys, key)
+		missing = append(missing, param)
+	}
+	if len(missing) == 0 {
+		return found, nil
+	}
+	params = missing
+	read := cr.startCacheRead()
+
+	if cr.storage == StorageHash {
+		err := cr.getManyHash(read, found, params, keys)
+		if err != nil {
+			return nil, err
+		}
+		return found, nil
+	}
+
+	values, err := cr.store.MGet(context.TODO(), keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	var hydrated []string
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var item T
+		errorUnmarshal := json.Unmarshal([]byte(raw), &item)
+		if errorUnmarshal != nil {
+			continue
+		}
+
+		found[params[i]] = item
+		hydrated = append(hydrated, cr.withSummary(keys[i])...)
+		read.remember(keys[i], item)
+	}
+
+	errExpire := cr.store.ExpireMany(context.TODO(), hydrated, INDIVIDUAL_KEY_TTL)
+	if errExpire != nil {
+		return nil, errExpire
+	}
+
+	return found, nil
+}
+
 func (cr *Base[T]) Set(item T, param ...string) error {
 	if len(param) > 0 {
-		return errors.New("only accept one param")
+		return fmt.Errorf("%w: only accept one param", ErrInvalidParam)
 	}
 
-	var key string
+	randId := item.GetRandId()
 	if param != nil {
-		key = fmt.Sprintf(cr.itemKeyFormat, param[0])
-	} else {
-		key = fmt.Sprintf(cr.itemKeyFormat, item.GetRandId())
+		randId = param[0]
+	}
+
+	key, errKey := cr.itemKey(randId)
+	if errKey != nil {
+		return errKey
+	}
+
+	if cr.storage == StorageHash {
+		err := cr.setHash(key, item)
+		if err != nil {
+			return err
+		}
+		return cr.invalidate(EventItemUpdated, randId, key)
 	}
 
 	itemInByte, errorMarshalJson := json.Marshal(item)
@@ -169,159 +283,315 @@ func (cr *Base[T]) Set(item T, param ...string) error {
 	}
 
 	valueAsString := string(itemInByte)
-	setRedis := cr.client.Set(
+	errSet := cr.store.Set(
 		context.TODO(),
 		key,
 		valueAsString,
 		INDIVIDUAL_KEY_TTL,
 	)
-	if setRedis.Err() != nil {
-		return setRedis.Err()
+	if errSet != nil {
+		return errSet
 	}
 
-	return nil
+	if len(cr.summaryFields) > 0 {
+		errSetSummary := cr.setSummary(key, itemInByte)
+		if errSetSummary != nil {
+			return errSetSummary
+		}
+	}
+
+	return cr.invalidate(EventItemUpdated, randId, key)
 }
 
+// Del removes the item and leaves a tombstone so seeding runs already in
+// flight do not bring it back.
 func (cr *Base[T]) Del(item T) error {
-	key := fmt.Sprintf(cr.itemKeyFormat, item.GetRandId())
+	return cr.del(context.TODO(), item, true)
+}
 
-	deleteRedis := cr.client.Del(
-		context.TODO(),
-		key,
-	)
-	if deleteRedis.Err() != nil {
-		return deleteRedis.Err()
+// del removes the item. Purges evict items that still exist in the database,
+// so they go without a tombstone and the next seed can restore them.
+func (cr *Base[T]) del(ctx context.Context, item T, tombstone bool) error {
+	key, errKey := cr.itemKey(item.GetRandId())
+	if errKey != nil {
+		return errKey
 	}
 
-	return nil
+	keys := []string{key}
+	if len(cr.summaryFields) > 0 {
+		keys = append(keys, key+":summary")
+	}
+
+	// the tombstone goes first, see TOMBSTONE_TTL
+	if tombstone {
+		errTombstone := cr.tombstone(ctx, item.GetRandId())
+		if errTombstone != nil {
+			return errTombstone
+		}
+	}
+
+	errDelete := cr.store.Del(ctx, keys...)
+	if errDelete != nil {
+		return errDelete
+	}
+
+	return cr.invalidate(EventItemDeleted, item.GetRandId(), key)
 }
 
 func NewBase[T item.Blueprint](client redis.UniversalClient, itemKeyFormat string) *Base[T] {
+	return NewBaseWithStore[T](NewRedisStore(client), itemKeyFormat)
+}
+
+func NewBaseWithStore[T item.Blueprint](store Store, itemKeyFormat string) *Base[T] {
+	return &Base[T]{
+		store:         store,
+		itemKeyFormat: itemKeyFormat,
+		storage:       StorageJSON,
+		tombstoneTTL:  TOMBSTONE_TTL,
+		bus:           NewInvalidationBus(store),
+	}
+}
+
+// NewHashBase stores each item as a Redis hash with one field per JSON field
+// of T, which enables UpdateFields, IncrementField and GetFields.
+func NewHashBase[T item.Blueprint](client redis.UniversalClient, itemKeyFormat string) *Base[T] {
+	return NewHashBaseWithStore[T](NewRedisStore(client), itemKeyFormat)
+}
+
+func NewHashBaseWithStore[T item.Blueprint](store Store, itemKeyFormat string) *Base[T] {
 	return &Base[T]{
-		client:        client,
+		store:         store,
 		itemKeyFormat: itemKeyFormat,
+		storage:       StorageHash,
+		tombstoneTTL:  TOMBSTONE_TTL,
+		bus:           NewInvalidationBus(store),
 	}
 }
 
 type SortedSet[T item.Blueprint] struct {
-	client             redis.UniversalClient
+	store              Store
 	sortedSetKeyFormat string
+	keyTemplate        *KeyTemplate
+	hashTags           bool
+	tombstoneTTL       time.Duration
+	memberships        bool
 }
 
 func (cr *SortedSet[T]) SetSortedSet(param []string, score float64, item T) error {
-	var key string
-	if param == nil {
-		key = cr.sortedSetKeyFormat
-	} else {
-		key = joinParam(cr.sortedSetKeyFormat, param)
+	return cr.setSortedSet(context.TODO(), param, score, item)
+}
+
+func (cr *SortedSet[T]) setSortedSet(ctx context.Context, param []string, score float64, item T) error {
+	key, errKey := cr.key(param)
+	if errKey != nil {
+		return errKey
 	}
 
-	sortedSetMember := redis.Z{
+	sortedSetMember := ZMember{
 		Score:  score,
 		Member: item.GetRandId(),
 	}
 
-	setSortedSet := cr.client.ZAdd(
-		context.TODO(),
-		key,
-		sortedSetMember)
-	if setSortedSet.Err() != nil {
-		return setSortedSet.Err()
-	}
-
-	setExpire := cr.client.Expire(
-		context.TODO(),
+	errSetSortedSet := cr.store.ZAdd(
+		ctx,
 		key,
 		SORTED_SET_TTL,
-	)
-	if !setExpire.Val() {
-		return setExpire.Err()
+		sortedSetMember)
+	if errSetSortedSet != nil {
+		return errSetSortedSet
 	}
+	recordRefreshed(ctx, key, sortedSetMember.Member)
 
-	return nil
+	return cr.track(ctx, param, item.GetRandId())
 }
 
 func (cr *SortedSet[T]) DeleteFromSortedSet(param []string, item T) error {
-	key := joinParam(cr.sortedSetKeyFormat, param)
+	return cr.deleteFromSortedSet(context.TODO(), param, item)
+}
 
-	removeFromSortedSet := cr.client.ZRem(
-		context.TODO(),
+func (cr *SortedSet[T]) deleteFromSortedSet(ctx context.Context, param []string, item T) error {
+	key, errKey := cr.key(param)
+	if errKey != nil {
+		return errKey
+	}
+
+	errRemoveFromSortedSet := cr.store.ZRem(
+		ctx,
 		key,
 		item.GetRandId(),
 	)
-	if removeFromSortedSet.Err() != nil {
-		return removeFromSortedSet.Err()
+	if errRemoveFromSortedSet != nil {
+		return errRemoveFromSortedSet
 	}
 
-	return nil
+	return cr.untrack(ctx, param, item.GetRandId())
 }
 
 func (cr *SortedSet[T]) TotalItemOnSortedSet(param []string) int64 {
-	key := joinParam(cr.sortedSetKeyFormat, param)
+	return cr.totalItems(context.TODO(), param)
+}
 
-	getTotalItemSortedSet := cr.client.ZCard(context.TODO(), key)
-	if getTotalItemSortedSet.Err() != nil {
+func (cr *SortedSet[T]) totalItems(ctx context.Context, param []string) int64 {
+	key, err := cr.key(param)
+	if err != nil {
 		return 0
 	}
 
-	return getTotalItemSortedSet.Val()
+	totalItemSortedSet, err := cr.store.ZCard(ctx, key)
+	if err != nil {
+		return 0
+	}
+
+	return totalItemSortedSet
 }
 
 func (cr *SortedSet[T]) DeleteSortedSet(param []string) error {
-	key := joinParam(cr.sortedSetKeyFormat, param)
+	key, errKey := cr.key(param)
+	if errKey != nil {
+		return errKey
+	}
 
-	removeSortedSet := cr.client.Del(context.TODO(), key)
-	if removeSortedSet.Err() != nil {
-		return removeSortedSet.Err()
+	errRemoveSortedSet := cr.store.Del(context.TODO(), key)
+	if errRemoveSortedSet != nil {
+		return errRemoveSortedSet
 	}
 
 	return nil
 }
 
 func (cr *SortedSet[T]) LowestScore(param []string) (float64, error) {
-	key := joinParam(cr.sortedSetKeyFormat, param)
+	return cr.lowestScore(context.TODO(), param)
+}
+
+func (cr *SortedSet[T]) lowestScore(ctx context.Context, param []string) (float64, error) {
+	key, errKey := cr.key(param)
+	if errKey != nil {
+		return 0, errKey
+	}
 
-	result, err := cr.client.ZRangeWithScores(context.TODO(), key, 0, 0).Result()
+	result, err := cr.store.ZRange(ctx, key, 0, 0, false)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get lowest score: %w", err)
 	}
 
 	if len(result) == 0 {
-		return 0, fmt.Errorf("sorted set is empty")
+		return 0, fmt.Errorf("%w: %s", ErrEmptySortedSet, key)
 	}
 
 	return result[0].Score, nil
 }
 
 func (cr *SortedSet[T]) HighestScore(param []string) (float64, error) {
-	key := joinParam(cr.sortedSetKeyFormat, param)
+	return cr.highestScore(context.TODO(), param)
+}
+
+func (cr *SortedSet[T]) highestScore(ctx context.Context, param []string) (float64, error) {
+	key, errKey := cr.key(param)
+	if errKey != nil {
+		return 0, errKey
+	}
 
-	result, err := cr.client.ZRangeWithScores(context.TODO(), key, -1, -1).Result()
+	result, err := cr.store.ZRange(ctx, key, -1, -1, false)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get highest score: %w", err)
 	}
 
 	if len(result) == 0 {
-		return 0, fmt.Errorf("sorted set is empty")
+		return 0, fmt.Errorf("%w: %s", ErrEmptySortedSet, key)
 	}
 
 	return result[0].Score, nil
 }
 
+// TrimSortedSet caps the sorted set at maxLength members. Descending sets keep
+// the highest scores and ascending sets the lowest, i.e. the head of the list
+// in either direction. It returns the number of members removed.
+func (cr *SortedSet[T]) TrimSortedSet(param []string, maxLength int64, direction string) (int64, error) {
+	return cr.trim(context.TODO(), param, maxLength, direction)
+}
+
+func (cr *SortedSet[T]) trim(ctx context.Context, param []string, maxLength int64, direction string) (int64, error) {
+	key, errKey := cr.key(param)
+	if errKey != nil {
+		return 0, errKey
+	}
+
+	if direction == Ascending {
+		return cr.store.ZRemRangeByRank(ctx, key, maxLength, -1)
+	}
+	return cr.store.ZRemRangeByRank(ctx, key, 0, -maxLength-1)
+}
+
+// isMarked reports whether the page marker suffix, e.g. ":firstpage", is set
+// on the sorted set of param.
+func (cr *SortedSet[T]) isMarked(ctx context.Context, param []string, suffix string) (bool, error) {
+	sortedSetKey, errKey := cr.key(param)
+	if errKey != nil {
+		return false, errKey
+	}
+
+	marker, err := cr.store.Get(ctx, sortedSetKey+suffix)
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return marker == "1", nil
+}
+
+func (cr *SortedSet[T]) unmark(ctx context.Context, param []string, suffix string) error {
+	sortedSetKey, errKey := cr.key(param)
+	if errKey != nil {
+		return errKey
+	}
+	return cr.store.Del(ctx, sortedSetKey+suffix)
+}
+
+// unmarkAll clears every marker of suffixes with one DEL.
+func (cr *SortedSet[T]) unmarkAll(ctx context.Context, param []string, suffixes []string) error {
+	if len(suffixes) == 0 {
+		return nil
+	}
+
+	sortedSetKey, errKey := cr.key(param)
+	if errKey != nil {
+		return errKey
+	}
+
+	keys := make([]string, len(suffixes))
+	for i, suffix := range suffixes {
+		keys[i] = sortedSetKey + suffix
+	}
+	return cr.store.Del(ctx, keys...)
+}
+
 func NewSortedSet[T item.Blueprint](client redis.UniversalClient, sortedSetKeyFormat string) *SortedSet[T] {
+	return NewSortedSetWithStore[T](NewRedisStore(client), sortedSetKeyFormat)
+}
+
+func NewSortedSetWithStore[T item.Blueprint](store Store, sortedSetKeyFormat string) *SortedSet[T] {
 	return &SortedSet[T]{
-		client:             client,
+		store:              store,
 		sortedSetKeyFormat: sortedSetKeyFormat,
+		tombstoneTTL:       TOMBSTONE_TTL,
 	}
 }
 
 type Paginate[T item.Blueprint] struct {
-	client           redis.UniversalClient
-	baseClient       *Base[T]
-	sortedSetClient  *SortedSet[T]
-	itemPerPage      int64
-	direction        string
-	sortingReference string
+	store             Store
+	baseClient        *Base[T]
+	sortedSetClient   *SortedSet[T]
+	itemPerPage       int64
+	direction         string
+	sortingReference  string
+	maxLength         int64
+	fanOutConcurrency int
+	metrics           Metrics
+	tracer            trace.Tracer
+	strict            bool
+	binding           *fieldBinding
+	revalidation      *revalidation
 }
 
 func (cr *Paginate[T]) GetItemPerPage() int64 {
@@ -332,107 +602,236 @@ func (cr *Paginate[T]) GetDirection() string {
 	return cr.direction
 }
 
+// SetMaxLength caps every sorted set of this Paginate at maxLength members.
+// Zero, the default, leaves them unbounded.
+func (cr *Paginate[T]) SetMaxLength(maxLength int64) {
+	cr.maxLength = maxLength
+}
+
+func (cr *Paginate[T]) GetMaxLength() int64 {
+	return cr.maxLength
+}
+
 func (cr *Paginate[T]) AddItem(item T, sortedSetParam []string) error {
 	return cr.IngestItem(item, sortedSetParam, false)
 }
 
 func (cr *Paginate[T]) IngestItem(item T, sortedSetParam []string, seed bool) error {
+	return cr.IngestItemContext(context.Background(), item, sortedSetParam, seed)
+}
+
+// IngestItemContext is IngestItem with its span and store calls under ctx.
+func (cr *Paginate[T]) IngestItemContext(ctx context.Context, item T, sortedSetParam []string, seed bool) error {
+	ctx, span := cr.StartSpan(ctx, "Paginate.IngestItem")
+	span.SetAttributes(AttributeSeeded.Bool(seed))
+
+	err := cr.ingestItem(ctx, item, sortedSetParam, seed)
+	if err == errTombstoned {
+		span.SetAttributes(AttributeTombstoned.Bool(true))
+		err = nil
+	}
+	EndSpan(span, err)
+	return err
+}
+
+func (cr *Paginate[T]) ingestItem(ctx context.Context, item T, sortedSetParam []string, seed bool) error {
 	if cr.direction == "" {
-		return errors.New("must set direction!")
+		return ErrDirectionUnset
 	}
 
-	score, err := getItemScore(item, cr.sortingReference)
+	sortedSetParam, err := bindParams(cr.binding, item, sortedSetParam)
 	if err != nil {
 		return err
 	}
 
-	isFirstPage, err := cr.IsFirstPage(sortedSetParam)
+	score, err := getItemScore(item, cr.sortingReference)
 	if err != nil {
 		return err
 	}
 
-	isLastPage, err := cr.IsLastPage(sortedSetParam)
+	if seed {
+		err = cr.sortedSetClient.setSortedSet(ctx, sortedSetParam, score, item)
+		if err != nil {
+			return err
+		}
+
+		err = unseedTombstoned(ctx, cr.baseClient, cr.sortedSetClient, sortedSetParam, item)
+		if err != nil {
+			return err
+		}
+		return cr.trimSortedSet(ctx, sortedSetParam)
+	}
+
+	err = cr.sortedSetClient.clearTombstone(ctx, sortedSetParam, item.GetRandId())
 	if err != nil {
 		return err
 	}
 
-	currentItemScore := float64(item.GetCreatedAt().UnixMilli())
+	admit, err := cr.admits(ctx, sortedSetParam, float64(item.GetCreatedAt().UnixMilli()))
+	if err != nil || !admit {
+		return err
+	}
 
-	if !seed {
-		isBlankPage, errGet := cr.IsBlankPage(sortedSetParam)
-		if errGet != nil {
-			return errGet
-		}
-		if isBlankPage {
-			cr.DelBlankPage(sortedSetParam)
-		}
+	return cr.addToSortedSet(ctx, sortedSetParam, score, item)
+}
 
-		if cr.direction == Descending {
-			if cr.sortedSetClient.TotalItemOnSortedSet(sortedSetParam) > 0 {
-				lowestScore, err := cr.sortedSetClient.LowestScore(sortedSetParam)
-				if err != nil {
-					return err
-				}
+// admits updates the page markers for an item added outside of seeding and
+// reports whether the item falls within the part of the list that is cached.
+func (cr *Paginate[T]) admits(ctx context.Context, sortedSetParam []string, currentItemScore float64) (bool, error) {
+	admit, unmarks, err := cr.admission(ctx, sortedSetParam, currentItemScore)
+	if err != nil {
+		return false, err
+	}
+	return admit, cr.sortedSetClient.unmarkAll(ctx, sortedSetParam, unmarks)
+}
 
-				if currentItemScore >= lowestScore {
-					if cr.sortedSetClient.TotalItemOnSortedSet(sortedSetParam) == cr.itemPerPage && isFirstPage {
-						cr.DelFirstPage(sortedSetParam)
-					}
-					return cr.sortedSetClient.SetSortedSet(sortedSetParam, score, item)
-				}
+// admission is admits without the writes: it also returns the page markers
+// that adding the item clears.
+func (cr *Paginate[T]) admission(ctx context.Context, sortedSetParam []string, currentItemScore float64) (bool, []string, error) {
+	isFirstPage, err := cr.sortedSetClient.isMarked(ctx, sortedSetParam, ":firstpage")
+	if err != nil {
+		return false, nil, err
+	}
+
+	isLastPage, err := cr.sortedSetClient.isMarked(ctx, sortedSetParam, ":lastpage")
+	if err != nil {
+		return false, nil, err
+	}
+
+	var unmarks []string
+	isBlankPage, errGet := cr.sortedSetClient.isMarked(ctx, sortedSetParam, ":blankpage")
+	if errGet != nil {
+		return false, nil, errGet
+	}
+	if isBlankPage {
+		unmarks = append(unmarks, ":blankpage")
+	}
+
+	if cr.direction == Descending {
+		if cr.sortedSetClient.totalItems(ctx, sortedSetParam) > 0 {
+			lowestScore, err := cr.sortedSetClient.lowestScore(ctx, sortedSetParam)
+			if err != nil {
+				return false, nil, err
 			}
-		} else if cr.direction == Ascending {
-			if cr.sortedSetClient.TotalItemOnSortedSet(sortedSetParam) > 0 {
-				highestScore, err := cr.sortedSetClient.HighestScore(sortedSetParam)
-				if err != nil {
-					return err
+
+			if currentItemScore >= lowestScore {
+				if cr.sortedSetClient.totalItems(ctx, sortedSetParam) == cr.itemPerPage && isFirstPage {
+					unmarks = append(unmarks, ":firstpage")
 				}
+				return true, unmarks, nil
+			}
+		}
+	} else if cr.direction == Ascending {
+		if cr.sortedSetClient.totalItems(ctx, sortedSetParam) > 0 {
+			highestScore, err := cr.sortedSetClient.highestScore(ctx, sortedSetParam)
+			if err != nil {
+				return false, nil, err
+			}
 
-				if currentItemScore <= highestScore {
-					if cr.sortedSetClient.TotalItemOnSortedSet(sortedSetParam) == cr.itemPerPage && isFirstPage {
-						return cr.DelFirstPage(sortedSetParam)
-					}
-					if isFirstPage || isLastPage {
-						return cr.sortedSetClient.SetSortedSet(sortedSetParam, score, item)
-					}
+			if currentItemScore <= highestScore {
+				if cr.sortedSetClient.totalItems(ctx, sortedSetParam) == cr.itemPerPage && isFirstPage {
+					return false, append(unmarks, ":firstpage"), nil
+				}
+				if isFirstPage || isLastPage {
+					return true, unmarks, nil
 				}
 			}
 		}
-	} else {
-		return cr.sortedSetClient.SetSortedSet(sortedSetParam, score, item)
 	}
 
+	return false, unmarks, nil
+}
+
+// addToSortedSet stores the item and trims the set back to maxLength.
+func (cr *Paginate[T]) addToSortedSet(ctx context.Context, param []string, score float64, item T) error {
+	err := cr.sortedSetClient.setSortedSet(ctx, param, score, item)
+	if err != nil {
+		return err
+	}
+
+	return cr.trimSortedSet(ctx, param)
+}
+
+// trimSortedSet cuts the set back to maxLength after an item was added. Once
+// the tail has been cut the cache no longer reaches the end of the list, so
+// the last page marker is cleared and the seeder fetches the tail again.
+func (cr *Paginate[T]) trimSortedSet(ctx context.Context, param []string) error {
+	if cr.maxLength <= 0 {
+		observeSize(ctx, cr.store, cr.metrics, cr.sortedSetClient, param)
+		return nil
+	}
+
+	removed, err := cr.sortedSetClient.trim(ctx, param, cr.maxLength, cr.direction)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		err = cr.sortedSetClient.unmark(ctx, param, ":lastpage")
+		if err != nil {
+			return err
+		}
+	}
+
+	observeSize(ctx, cr.store, cr.metrics, cr.sortedSetClient, param)
 	return nil
 }
 
 func (cr *Paginate[T]) RemoveItem(item T, param []string) error {
-	err := cr.sortedSetClient.DeleteFromSortedSet(param, item)
+	return cr.RemoveItemContext(context.Background(), item, param)
+}
+
+// RemoveItemContext is RemoveItem with its span and store calls under ctx.
+func (cr *Paginate[T]) RemoveItemContext(ctx context.Context, item T, param []string) error {
+	ctx, span := cr.StartSpan(ctx, "Paginate.RemoveItem")
+	err := cr.removeItem(ctx, item, param)
+	EndSpan(span, err)
+	return err
+}
+
+func (cr *Paginate[T]) removeItem(ctx context.Context, item T, param []string) error {
+	param, err := bindParams(cr.binding, item, param)
 	if err != nil {
 		return err
 	}
 
-	isFirstPage, errFirstPage := cr.IsFirstPage(param)
+	return cr.removeFrom(ctx, item, param)
+}
+
+// removeFrom removes item from the sorted set of param and clears the page
+// markers the set no longer backs.
+func (cr *Paginate[T]) removeFrom(ctx context.Context, item T, param []string) error {
+	err := cr.sortedSetClient.tombstone(ctx, param, item.GetRandId())
+	if err != nil {
+		return err
+	}
+
+	err = cr.sortedSetClient.deleteFromSortedSet(ctx, param, item)
+	if err != nil {
+		return err
+	}
+
+	isFirstPage, errFirstPage := cr.sortedSetClient.isMarked(ctx, param, ":firstpage")
 	if errFirstPage != nil {
 		return errFirstPage
 	}
 	if isFirstPage {
-		numItem := cr.sortedSetClient.TotalItemOnSortedSet(param) // O(log(n))
+		numItem := cr.sortedSetClient.totalItems(ctx, param) // O(log(n))
 		if numItem == 0 {
-			errRemFirstPage := cr.DelFirstPage(param)
+			errRemFirstPage := cr.sortedSetClient.unmark(ctx, param, ":firstpage")
 			if errRemFirstPage != nil {
 				return errRemFirstPage
 			}
 		}
 	}
 
-	isLastPage, errLastPage := cr.IsLastPage(param)
+	isLastPage, errLastPage := cr.sortedSetClient.isMarked(ctx, param, ":lastpage")
 	if errLastPage != nil {
 		return errLastPage
 	}
 	if isLastPage {
-		numItem := cr.sortedSetClient.TotalItemOnSortedSet(param)
+		numItem := cr.sortedSetClient.totalItems(ctx, param)
 		if numItem == 0 {
-			errRemLastPage := cr.DelLastPage(param)
+			errRemLastPage := cr.sortedSetClient.unmark(ctx, param, ":lastpage")
 			if errRemLastPage != nil {
 				return errRemLastPage
 			}
@@ -443,145 +842,87 @@ func (cr *Paginate[T]) RemoveItem(item T, param []string) error {
 }
 
 func (cr *Paginate[T]) IsFirstPage(param []string) (bool, error) {
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
-	fistPageKey := sortedSetKey + ":firstpage"
-
-	getFirstPageKey := cr.client.Get(context.TODO(), fistPageKey)
-	if getFirstPageKey.Err() != nil {
-		if getFirstPageKey.Err() == redis.Nil {
-			return false, nil
-		} else {
-			return false, getFirstPageKey.Err()
-		}
-	}
-
-	if getFirstPageKey.Val() == "1" {
-		return true, nil
-	}
-	return false, nil
+	return cr.sortedSetClient.isMarked(context.TODO(), param, ":firstpage")
 }
 
 func (cr *Paginate[T]) SetFirstPage(param []string) error {
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
+	sortedSetKey, errKey := cr.sortedSetClient.key(param)
+	if errKey != nil {
+		return errKey
+	}
 	firstPageKey := sortedSetKey + ":firstpage"
 
-	setFirstPageKey := cr.client.Set(
+	errSetFirstPageKey := cr.store.Set(
 		context.TODO(),
 		firstPageKey,
-		1,
+		"1",
 		SORTED_SET_TTL,
 	)
 
-	if setFirstPageKey.Err() != nil {
-		return setFirstPageKey.Err()
+	if errSetFirstPageKey != nil {
+		return errSetFirstPageKey
 	}
 	return nil
 }
 
 func (cr *Paginate[T]) DelFirstPage(param []string) error {
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
-	firstPageKey := sortedSetKey + ":firstpage"
-
-	setFirstPageKey := cr.client.Del(context.TODO(), firstPageKey)
-	if setFirstPageKey.Err() != nil {
-		return setFirstPageKey.Err()
-	}
-
-	return nil
+	return cr.sortedSetClient.unmark(context.TODO(), param, ":firstpage")
 }
 
 func (cr *Paginate[T]) IsLastPage(param []string) (bool, error) {
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
-	lastPageKey := sortedSetKey + ":lastpage"
-
-	getLastPageKey := cr.client.Get(context.TODO(), lastPageKey)
-	if getLastPageKey.Err() != nil {
-		if getLastPageKey.Err() == redis.Nil {
-			return false, nil
-		} else {
-			return false, getLastPageKey.Err()
-		}
-	}
-
-	if getLastPageKey.Val() == "1" {
-		return true, nil
-	}
-	return false, nil
+	return cr.sortedSetClient.isMarked(context.TODO(), param, ":lastpage")
 }
 
 func (cr *Paginate[T]) SetLastPage(param []string) error {
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
+	sortedSetKey, errKey := cr.sortedSetClient.key(param)
+	if errKey != nil {
+		return errKey
+	}
 	lastPageKey := sortedSetKey + ":lastpage"
 
-	setLastPageKey := cr.client.Set(
+	errSetLastPageKey := cr.store.Set(
 		context.TODO(),
 		lastPageKey,
-		1,
+		"1",
 		SORTED_SET_TTL,
 	)
 
-	if setLastPageKey.Err() != nil {
-		return setLastPageKey.Err()
+	if errSetLastPageKey != nil {
+		return errSetLastPageKey
 	}
 	return nil
 }
 
 func (cr *Paginate[T]) DelLastPage(param []string) error {
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
-	lastPageKey := sortedSetKey + ":lastpage"
-
-	delLastPageKey := cr.client.Del(context.TODO(), lastPageKey)
-	if delLastPageKey.Err() != nil {
-		return delLastPageKey.Err()
-	}
-	return nil
+	return cr.sortedSetClient.unmark(context.TODO(), param, ":lastpage")
 }
 
 func (cr *Paginate[T]) IsBlankPage(param []string) (bool, error) {
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
-	lastPageKey := sortedSetKey + ":blankpage"
-
-	getLastPageKey := cr.client.Get(context.TODO(), lastPageKey)
-	if getLastPageKey.Err() != nil {
-		if getLastPageKey.Err() == redis.Nil {
-			return false, nil
-		} else {
-			return false, getLastPageKey.Err()
-		}
-	}
-
-	if getLastPageKey.Val() == "1" {
-		return true, nil
-	}
-	return false, nil
+	return cr.sortedSetClient.isMarked(context.TODO(), param, ":blankpage")
 }
 
 func (cr *Paginate[T]) SetBlankPage(param []string) error {
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
+	sortedSetKey, errKey := cr.sortedSetClient.key(param)
+	if errKey != nil {
+		return errKey
+	}
 	lastPageKey := sortedSetKey + ":blankpage"
 
-	setLastPageKey := cr.client.Set(
+	errSetLastPageKey := cr.store.Set(
 		context.TODO(),
 		lastPageKey,
-		1,
+		"1",
 		SORTED_SET_TTL,
 	)
 
-	if setLastPageKey.Err() != nil {
-		return setLastPageKey.Err()
+	if errSetLastPageKey != nil {
+		return errSetLastPageKey
 	}
 	return nil
 }
 
 func (cr *Paginate[T]) DelBlankPage(param []string) error {
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
-	lastPageKey := sortedSetKey + ":blankpage"
-
-	delLastPageKey := cr.client.Del(context.TODO(), lastPageKey)
-	if delLastPageKey.Err() != nil {
-		return delLastPageKey.Err()
-	}
-	return nil
+	return cr.sortedSetClient.unmark(context.TODO(), param, ":blankpage")
 }
 
 func (cr *Paginate[T]) Fetch(
@@ -590,79 +931,190 @@ func (cr *Paginate[T]) Fetch(
 	processorArgs []interface{},
 	processor func(item *T, args []interface{}),
 ) ([]T, string, string, error) {
-	var items []T
-	var validLastRandId string
-	var position string
+	return cr.FetchContext(context.Background(), param, lastRandIds, processorArgs, processor)
+}
+
+// FetchContext is Fetch with its span and store calls under ctx.
+func (cr *Paginate[T]) FetchContext(
+	ctx context.Context,
+	param []string,
+	lastRandIds []string,
+	processorArgs []interface{},
+	processor func(item *T, args []interface{}),
+) ([]T, string, string, error) {
+	ctx, span := cr.StartSpan(ctx, "Paginate.Fetch")
+	dangling := &danglingCounter{Metrics: cr.GetMetrics()}
+
+	items, validLastRandId, position, err := cr.fetch(ctx, dangling, param, lastRandIds, processorArgs, processor)
+
+	span.SetAttributes(
+		AttributeItemsReturned.Int(len(items)),
+		AttributeDanglingSkipped.Int(dangling.count),
+	)
+	EndSpan(span, err)
+
+	return items, validLastRandId, position, err
+}
 
+func (cr *Paginate[T]) fetch(
+	ctx context.Context,
+	metrics Metrics,
+	param []string,
+	lastRandIds []string,
+	processorArgs []interface{},
+	processor func(item *T, args []interface{}),
+) ([]T, string, string, error) {
 	// safety net
 	if cr.direction == "" {
-		return nil, validLastRandId, position, errors.New("must set direction!")
+		return nil, "", "", ErrDirectionUnset
+	}
+
+	sortedSetKey, errKey := cr.sortedSetClient.key(param)
+	if errKey != nil {
+		return nil, "", "", errKey
+	}
+
+	items, validLastRandId, position, err := fetchPage(
+		ctx,
+		cr.store,
+		cr.baseClient,
+		sortedSetKey,
+		cr.sortedSetClient.sortedSetKeyFormat,
+		metrics,
+		cr.direction,
+		cr.itemPerPage,
+		lastRandIds,
+		nil,
+		processorArgs,
+		processor,
+	)
+	err = strictResult(cr.strict, err)
+	if err != nil && !isPartial(err) {
+		return nil, validLastRandId, position, err
 	}
 
-	sortedSetKey := joinParam(cr.sortedSetClient.sortedSetKeyFormat, param)
+	cr.store.Expire(ctx, sortedSetKey, SORTED_SET_TTL)
+	cr.revalidation.check(ctx, cr.store, sortedSetKey, param, len(items) > 0)
+
+	return items, validLastRandId, position, err
+}
+
+// fetchPage resolves the cursor from lastRandIds and reads one page of
+// sortedSetKey. It is shared by Paginate and Query, which differ only in how
+// the key is built and how its TTL is managed.
+func fetchPage[T item.Blueprint](
+	ctx context.Context,
+	store Store,
+	baseClient *Base[T],
+	sortedSetKey string,
+	keyFormat string,
+	metrics Metrics,
+	direction string,
+	itemPerPage int64,
+	lastRandIds []string,
+	fields []string,
+	processorArgs []interface{},
+	processor func(item *T, args []interface{}),
+) ([]T, string, string, error) {
+	var items []T
+	var validLastRandId string
+	var position string
+	var failures []*ItemError
+
 	start := int64(0)
-	stop := cr.itemPerPage - 1
+	stop := itemPerPage - 1
 
 	for i := len(lastRandIds) - 1; i >= 0; i-- {
-		item, err := cr.baseClient.Get(lastRandIds[i])
+		item, err := baseClient.Get(lastRandIds[i])
 		if err != nil {
 			continue
 		}
 
-		var rank *redis.IntCmd
-		if cr.direction == Descending {
-			rank = cr.client.ZRevRank(context.TODO(), sortedSetKey, item.GetRandId())
-		} else {
-			rank = cr.client.ZRank(context.TODO(), sortedSetKey, item.GetRandId())
-		}
-
-		if rank.Err() == nil {
+		rank, errRank := store.ZRank(ctx, sortedSetKey, item.GetRandId(), direction == Descending)
+		if errRank == nil {
 			validLastRandId = item.GetRandId()
-			start = rank.Val() + 1
-			stop = start + cr.itemPerPage - 1
+			start = rank + 1
+			stop = start + itemPerPage - 1
 			break
 		}
 	}
 
-	var listRandIds []string
-	var result *redis.StringSliceCmd
-	if cr.direction == Descending {
-		result = cr.client.ZRevRange(context.TODO(), sortedSetKey, start, stop)
-	} else {
-		result = cr.client.ZRange(context.TODO(), sortedSetKey, start, stop)
+	result, errRange := store.ZRange(ctx, sortedSetKey, start, stop, direction == Descending)
+	if errRange != nil {
+		return nil, validLastRandId, position, errRange
 	}
-	if result.Err() != nil {
-		return nil, validLastRandId, position, result.Err()
-	}
-	listRandIds = result.Val()
-
-	cr.client.Expire(context.TODO(), sortedSetKey, SORTED_SET_TTL)
+	listRandIds := zMembers(result)
 
-	for i := 0; i < len(listRandIds); i++ {
-		item, err := cr.baseClient.Get(listRandIds[i])
+	if fields != nil {
+		found, err := baseClient.getManyProjectedById(listRandIds, fields)
 		if err != nil {
-			continue
+			return nil, validLastRandId, position, err
 		}
-		if processor != nil {
-			processor(&item, processorArgs)
+
+		for i := 0; i < len(listRandIds); i++ {
+			item, ok := found[listRandIds[i]]
+			if !ok {
+				metricsOrNoop(metrics).DanglingItem(keyFormat)
+				continue
+			}
+			if processor != nil {
+				processor(&item, processorArgs)
+			}
+			items = append(items, item)
+			validLastRandId = listRandIds[i]
+		}
+	} else {
+		for i := 0; i < len(listRandIds); i++ {
+			item, err := baseClient.Get(listRandIds[i])
+			if err != nil {
+				if errors.Is(err, ErrNotFound) {
+					metricsOrNoop(metrics).DanglingItem(keyFormat)
+				} else {
+					failures = append(failures, &ItemError{RandId: listRandIds[i], Stage: StageRead, Err: err})
+				}
+				continue
+			}
+			if processor != nil {
+				processor(&item, processorArgs)
+			}
+			items = append(items, item)
+			validLastRandId = listRandIds[i]
 		}
-		items = append(items, item)
-		validLastRandId = listRandIds[i]
 	}
 
 	if start == 0 {
 		position = firstPage
-	} else if int64(len(listRandIds)) < cr.itemPerPage {
+	} else if int64(len(listRandIds)) < itemPerPage {
 		position = lastPage
 	} else {
 		position = middlePage
 	}
 
-	return items, validLastRandId, position, nil
+	return items, validLastRandId, position, partialError(failures)
 }
 
 func (cr *Paginate[T]) FetchAll(param []string) ([]T, error) {
-	return FetchAll(cr.client, cr.baseClient, cr.sortedSetClient, param, cr.direction)
+	return cr.FetchAllContext(context.Background(), param)
+}
+
+// FetchAllContext is FetchAll with its span and store calls under ctx.
+func (cr *Paginate[T]) FetchAllContext(ctx context.Context, param []string) ([]T, error) {
+	ctx, span := cr.StartSpan(ctx, "Paginate.FetchAll")
+	dangling := &danglingCounter{Metrics: cr.GetMetrics()}
+
+	items, err := fetchAll(ctx, cr.store, dangling, cr.baseClient, cr.sortedSetClient, param, cr.direction, nil)
+	err = strictResult(cr.strict, err)
+	if err != nil && !isPartial(err) {
+		items = nil
+	}
+
+	span.SetAttributes(
+		AttributeItemsReturned.Int(len(items)),
+		AttributeDanglingSkipped.Int(dangling.count),
+	)
+	EndSpan(span, err)
+
+	return items, err
 }
 
 func (cr *Paginate[T]) RequriesSeeding(param []string, totalItems int64) (bool, error) {
@@ -719,7 +1171,7 @@ func (cr *Paginate[T]) PurgePagination(param []string) error {
 	}
 
 	for _, item := range items {
-		cr.baseClient.Del(item)
+		cr.baseClient.del(context.TODO(), item, false)
 	}
 
 	err = cr.sortedSetClient.DeleteSortedSet(param)
@@ -742,48 +1194,32 @@ func (cr *Paginate[T]) PurgePagination(param []string) error {
 		return err
 	}
 
-	return nil
+	return publishPurge(cr.baseClient, cr.sortedSetClient, param)
 }
 
 func NewPaginateWithReference[T item.Blueprint](client redis.UniversalClient, baseClient *Base[T], keyFormat string, itemPerPage int64, direction string, sortingReference string) *Paginate[T] {
+	return NewPaginateWithStore[T](NewRedisStore(client), baseClient, keyFormat, itemPerPage, direction, sortingReference)
+}
+
+func NewPaginate[T item.Blueprint](client redis.UniversalClient, baseClient *Base[T], keyFormat string, itemPerPage int64, direction string) *Paginate[T] {
+	return NewPaginateWithStore[T](NewRedisStore(client), baseClient, keyFormat, itemPerPage, direction, "")
+}
+
+func NewPaginateWithStore[T item.Blueprint](store Store, baseClient *Base[T], keyFormat string, itemPerPage int64, direction string, sortingReference string) *Paginate[T] {
 	if direction != Ascending && direction != Descending {
 		direction = Descending
 	}
 
-	sortedSetClient := SortedSet[T]{
-		client:             client,
-		sortedSetKeyFormat: keyFormat,
-	}
-
 	return &Paginate[T]{
-		client:           client,
+		store:            store,
 		baseClient:       baseClient,
-		sortedSetClient:  &sortedSetClient,
+		sortedSetClient:  NewSortedSetWithStore[T](store, keyFormat),
 		itemPerPage:      itemPerPage,
 		direction:        direction,
 		sortingReference: sortingReference,
 	}
 }
 
-func NewPaginate[T item.Blueprint](client redis.UniversalClient, baseClient *Base[T], keyFormat string, itemPerPage int64, direction string) *Paginate[T] {
-	if direction != Ascending && direction != Descending {
-		direction = Descending
-	}
-
-	sortedSetClient := SortedSet[T]{
-		client:             client,
-		sortedSetKeyFormat: keyFormat,
-	}
-
-	return &Paginate[T]{
-		client:          client,
-		baseClient:      baseClient,
-		sortedSetClient: &sortedSetClient,
-		itemPerPage:     itemPerPage,
-		direction:       direction,
-	}
-}
-
 type Segment struct {
 	*item.Foundation `json:",inline" bson:",inline"`
 	Start            float64
@@ -805,7 +1241,7 @@ func NewSegment(start float64, end float64) Segment {
 }
 
 type SegmentManager[T item.Blueprint] struct {
-	client             redis.UniversalClient
+	store              Store
 	baseClient         *Base[Segment]
 	sortedSetClient    *SortedSet[Segment]
 	segmentDesignation string
@@ -823,14 +1259,17 @@ func (sm *SegmentManager[T]) AddSegment(start float64, end float64) {
 }
 
 func (sm *SegmentManager[T]) IsWithinSegment(start float64, end float64) *Segment {
-	keySegmentList := joinParam(sm.sortedSetClient.sortedSetKeyFormat, []string{sm.segmentDesignation})
+	keySegmentList, errKey := sm.sortedSetClient.key([]string{sm.segmentDesignation})
+	if errKey != nil {
+		return nil
+	}
 
-	segments, errFetchSegments := sm.client.ZRange(context.TODO(), keySegmentList, 0, -1).Result()
+	segments, errFetchSegments := sm.store.ZRange(context.TODO(), keySegmentList, 0, -1, false)
 	if errFetchSegments != nil {
 		return nil
 	}
 
-	for _, segmentRandId := range segments {
+	for _, segmentRandId := range zMembers(segments) {
 		segment, err := sm.baseClient.Get(segmentRandId)
 		if err != nil {
 			continue
@@ -844,30 +1283,30 @@ func (sm *SegmentManager[T]) IsWithinSegment(start float64, end float64) *Segmen
 }
 
 func NewSegmentManager[T item.Blueprint](client redis.UniversalClient, designation string) *SegmentManager[T] {
-	baseClient := &Base[Segment]{
-		client:        client,
-		itemKeyFormat: "segment:%s",
-	}
-
-	sortedSetClient := &SortedSet[Segment]{
-		client:             client,
-		sortedSetKeyFormat: "segments:%s",
-	}
+	return NewSegmentManagerWithStore[T](NewRedisStore(client), designation)
+}
 
+func NewSegmentManagerWithStore[T item.Blueprint](store Store, designation string) *SegmentManager[T] {
 	return &SegmentManager[T]{
 		segmentDesignation: designation,
-		client:             client,
-		baseClient:         baseClient,
-		sortedSetClient:    sortedSetClient,
+		store:              store,
+		baseClient:         NewBaseWithStore[Segment](store, "segment:%s"),
+		sortedSetClient:    NewSortedSetWithStore[Segment](store, "segments:%s"),
 	}
 }
 
 type Sorted[T item.Blueprint] struct {
-	client           redis.UniversalClient
+	store            Store
 	baseClient       *Base[T]
 	sortedSetClient  *SortedSet[T]
 	direction        string
 	sortingReference string
+	maxLength        int64
+	metrics          Metrics
+	tracer           trace.Tracer
+	strict           bool
+	binding          *fieldBinding
+	revalidation     *revalidation
 }
 
 func (srtd *Sorted[T]) SetDirection(direction string) {
@@ -878,88 +1317,197 @@ func (srtd *Sorted[T]) SetDirection(direction string) {
 	}
 }
 
+// SetMaxLength caps every sorted set of this Sorted at maxLength members,
+// keeping the head of the list in its direction. Zero leaves them unbounded.
+func (srtd *Sorted[T]) SetMaxLength(maxLength int64) {
+	srtd.maxLength = maxLength
+}
+
+func (srtd *Sorted[T]) GetMaxLength() int64 {
+	return srtd.maxLength
+}
+
 func (srtd *Sorted[T]) AddItem(item T, sortedSetParam []string) {
 	srtd.IngestItem(item, sortedSetParam, false)
 }
 
 func (srtd *Sorted[T]) IngestItem(item T, sortedSetParam []string, seed bool) error {
+	return srtd.IngestItemContext(context.Background(), item, sortedSetParam, seed)
+}
+
+func (srtd *Sorted[T]) IngestItemContext(ctx context.Context, item T, sortedSetParam []string, seed bool) error {
+	ctx, span := srtd.StartSpan(ctx, "Sorted.IngestItem")
+	span.SetAttributes(AttributeSeeded.Bool(seed))
+
+	err := srtd.ingestItem(ctx, item, sortedSetParam, seed)
+	if err == errTombstoned {
+		span.SetAttributes(AttributeTombstoned.Bool(true))
+		err = nil
+	}
+	EndSpan(span, err)
+	return err
+}
+
+func (srtd *Sorted[T]) ingestItem(ctx context.Context, item T, sortedSetParam []string, seed bool) error {
+	sortedSetParam, err := bindParams(srtd.binding, item, sortedSetParam)
+	if err != nil {
+		return err
+	}
+
 	score, err := getItemScore(item, srtd.sortingReference)
 	if err != nil {
 		return err
 	}
 
-	if !seed {
-		isBlankPage, errGet := srtd.IsBlankPage(sortedSetParam)
-		if errGet != nil {
-			return errGet
+	if seed {
+		err = srtd.sortedSetClient.setSortedSet(ctx, sortedSetParam, score, item)
+		if err != nil {
+			return err
 		}
-		if isBlankPage {
-			srtd.DelBlankPage(sortedSetParam)
+
+		err = unseedTombstoned(ctx, srtd.baseClient, srtd.sortedSetClient, sortedSetParam, item)
+		if err != nil {
+			return err
 		}
+		return srtd.trimSortedSet(ctx, sortedSetParam)
+	}
+
+	err = srtd.sortedSetClient.clearTombstone(ctx, sortedSetParam, item.GetRandId())
+	if err != nil {
+		return err
+	}
+
+	admit, err := srtd.admits(ctx, sortedSetParam)
+	if err != nil || !admit {
+		return err
+	}
+
+	return srtd.addToSortedSet(ctx, sortedSetParam, score, item)
+}
 
-		if srtd.sortedSetClient.TotalItemOnSortedSet(sortedSetParam) > 0 {
-			return srtd.sortedSetClient.SetSortedSet(sortedSetParam, score, item)
+// admits clears the blank page marker for an item added outside of seeding
+// and reports whether the sorted set is cached at all.
+func (srtd *Sorted[T]) admits(ctx context.Context, sortedSetParam []string) (bool, error) {
+	admit, unmarks, err := srtd.admission(ctx, sortedSetParam)
+	if err != nil {
+		return false, err
+	}
+	return admit, srtd.sortedSetClient.unmarkAll(ctx, sortedSetParam, unmarks)
+}
+
+// admission is admits without the write: it also returns the page markers
+// that adding the item clears.
+func (srtd *Sorted[T]) admission(ctx context.Context, sortedSetParam []string) (bool, []string, error) {
+	isBlankPage, errGet := srtd.sortedSetClient.isMarked(ctx, sortedSetParam, ":blankpage")
+	if errGet != nil {
+		return false, nil, errGet
+	}
+
+	var unmarks []string
+	if isBlankPage {
+		unmarks = append(unmarks, ":blankpage")
+	}
+	return srtd.sortedSetClient.totalItems(ctx, sortedSetParam) > 0, unmarks, nil
+}
+
+func (srtd *Sorted[T]) addToSortedSet(ctx context.Context, param []string, score float64, item T) error {
+	err := srtd.sortedSetClient.setSortedSet(ctx, param, score, item)
+	if err != nil {
+		return err
+	}
+
+	return srtd.trimSortedSet(ctx, param)
+}
+
+func (srtd *Sorted[T]) trimSortedSet(ctx context.Context, param []string) error {
+	if srtd.maxLength > 0 {
+		_, err := srtd.sortedSetClient.trim(ctx, param, srtd.maxLength, srtd.direction)
+		if err != nil {
+			return err
 		}
-	} else {
-		return srtd.sortedSetClient.SetSortedSet(sortedSetParam, score, item)
 	}
 
+	observeSize(ctx, srtd.store, srtd.metrics, srtd.sortedSetClient, param)
 	return nil
 }
 
 func (srtd *Sorted[T]) RemoveItem(item T, sortedSetParam []string) error {
-	return srtd.sortedSetClient.DeleteFromSortedSet(sortedSetParam, item)
+	return srtd.RemoveItemContext(context.Background(), item, sortedSetParam)
+}
+
+func (srtd *Sorted[T]) RemoveItemContext(ctx context.Context, item T, sortedSetParam []string) error {
+	ctx, span := srtd.StartSpan(ctx, "Sorted.RemoveItem")
+	sortedSetParam, err := bindParams(srtd.binding, item, sortedSetParam)
+	if err == nil {
+		err = srtd.removeFrom(ctx, item, sortedSetParam)
+	}
+	EndSpan(span, err)
+	return err
+}
+
+func (srtd *Sorted[T]) removeFrom(ctx context.Context, item T, param []string) error {
+	err := srtd.sortedSetClient.tombstone(ctx, param, item.GetRandId())
+	if err != nil {
+		return err
+	}
+
+	return srtd.sortedSetClient.deleteFromSortedSet(ctx, param, item)
 }
 
 func (srtd *Sorted[T]) Fetch(param []string) ([]T, error) {
-	return FetchAll[T](srtd.client, srtd.baseClient, srtd.sortedSetClient, param, srtd.direction)
+	return srtd.FetchContext(context.Background(), param)
+}
+
+func (srtd *Sorted[T]) FetchContext(ctx context.Context, param []string) ([]T, error) {
+	ctx, span := srtd.StartSpan(ctx, "Sorted.Fetch")
+	dangling := &danglingCounter{Metrics: srtd.GetMetrics()}
+
+	items, err := fetchAll(ctx, srtd.store, dangling, srtd.baseClient, srtd.sortedSetClient, param, srtd.direction, nil)
+	err = strictResult(srtd.strict, err)
+	if err != nil && !isPartial(err) {
+		items = nil
+	} else if srtd.revalidation != nil {
+		sortedSetKey, errKey := srtd.sortedSetClient.key(param)
+		if errKey == nil {
+			srtd.revalidation.check(ctx, srtd.store, sortedSetKey, param, len(items) > 0)
+		}
+	}
+
+	span.SetAttributes(
+		AttributeItemsReturned.Int(len(items)),
+		AttributeDanglingSkipped.Int(dangling.count),
+	)
+	EndSpan(span, err)
+
+	return items, err
 }
 
 func (srtd *Sorted[T]) SetBlankPage(param []string) error {
-	sortedSetKey := joinParam(srtd.sortedSetClient.sortedSetKeyFormat, param)
+	sortedSetKey, errKey := srtd.sortedSetClient.key(param)
+	if errKey != nil {
+		return errKey
+	}
 	lastPageKey := sortedSetKey + ":blankpage"
 
-	setLastPageKey := srtd.client.Set(
+	errSetLastPageKey := srtd.store.Set(
 		context.TODO(),
 		lastPageKey,
-		1,
+		"1",
 		SORTED_SET_TTL,
 	)
 
-	if setLastPageKey.Err() != nil {
-		return setLastPageKey.Err()
+	if errSetLastPageKey != nil {
+		return errSetLastPageKey
 	}
 	return nil
 }
 
 func (srtd *Sorted[T]) DelBlankPage(param []string) error {
-	sortedSetKey := joinParam(srtd.sortedSetClient.sortedSetKeyFormat, param)
-	lastPageKey := sortedSetKey + ":blankpage"
-
-	delLastPageKey := srtd.client.Del(context.TODO(), lastPageKey)
-	if delLastPageKey.Err() != nil {
-		return delLastPageKey.Err()
-	}
-	return nil
+	return srtd.sortedSetClient.unmark(context.TODO(), param, ":blankpage")
 }
 
 func (srtd *Sorted[T]) IsBlankPage(param []string) (bool, error) {
-	sortedSetKey := joinParam(srtd.sortedSetClient.sortedSetKeyFormat, param)
-	lastPageKey := sortedSetKey + ":blankpage"
-
-	getLastPageKey := srtd.client.Get(context.TODO(), lastPageKey)
-	if getLastPageKey.Err() != nil {
-		if getLastPageKey.Err() == redis.Nil {
-			return false, nil
-		} else {
-			return false, getLastPageKey.Err()
-		}
-	}
-
-	if getLastPageKey.Val() == "1" {
-		return true, nil
-	}
-	return false, nil
+	return srtd.sortedSetClient.isMarked(context.TODO(), param, ":blankpage")
 }
 
 func (srtd *Sorted[T]) RequireSeeding(param []string) (bool, error) {
@@ -994,7 +1542,7 @@ func (srtd *Sorted[T]) PurgeSorted(param []string) error {
 	}
 
 	for _, item := range items {
-		srtd.baseClient.Del(item)
+		srtd.baseClient.del(context.TODO(), item, false)
 	}
 
 	err = srtd.sortedSetClient.DeleteSortedSet(param)
@@ -1002,59 +1550,66 @@ func (srtd *Sorted[T]) PurgeSorted(param []string) error {
 		return err
 	}
 
-	return nil
+	return publishPurge(srtd.baseClient, srtd.sortedSetClient, param)
 }
 
 func NewSortedWithReference[T item.Blueprint](client redis.UniversalClient, baseClient *Base[T], keyFormat string, direction string, sortingReference string) *Sorted[T] {
-	sortedSetClient := &SortedSet[T]{
-		client:             client,
-		sortedSetKeyFormat: keyFormat,
-	}
+	return NewSortedWithStore[T](NewRedisStore(client), baseClient, keyFormat, direction, sortingReference)
+}
 
+func NewSorted[T item.Blueprint](client redis.UniversalClient, baseClient *Base[T], keyFormat string, direction string) *Sorted[T] {
+	return NewSortedWithStore[T](NewRedisStore(client), baseClient, keyFormat, direction, "")
+}
+
+func NewSortedWithStore[T item.Blueprint](store Store, baseClient *Base[T], keyFormat string, direction string, sortingReference string) *Sorted[T] {
 	return &Sorted[T]{
-		client:           client,
+		store:            store,
 		baseClient:       baseClient,
-		sortedSetClient:  sortedSetClient,
+		sortedSetClient:  NewSortedSetWithStore[T](store, keyFormat),
 		direction:        direction,
 		sortingReference: sortingReference,
 	}
 }
 
-func NewSorted[T item.Blueprint](client redis.UniversalClient, baseClient *Base[T], keyFormat string, direction string) *Sorted[T] {
-	sortedSetClient := &SortedSet[T]{
-		client:             client,
-		sortedSetKeyFormat: keyFormat,
-	}
-
-	return &Sorted[T]{
-		client:          client,
-		baseClient:      baseClient,
-		sortedSetClient: sortedSetClient,
-		direction:       direction,
-	}
+func FetchAll[T item.Blueprint](redisClient redis.UniversalClient, baseClient *Base[T], sortedSetClient *SortedSet[T], param []string, direction string) ([]T, error) {
+	return fetchAll(context.TODO(), NewRedisStore(redisClient), nil, baseClient, sortedSetClient, param, direction, nil)
 }
 
-func FetchAll[T item.Blueprint](redisClient redis.UniversalClient, baseClient *Base[T], sortedSetClient *SortedSet[T], param []string, direction string) ([]T, error) {
+func fetchAll[T item.Blueprint](ctx context.Context, store Store, metrics Metrics, baseClient *Base[T], sortedSetClient *SortedSet[T], param []string, direction string, fields []string) ([]T, error) {
 	var items []T
 	var extendTTL bool
+	var failures []*ItemError
 
 	if direction == "" {
-		return nil, errors.New("must set direction!")
+		return nil, ErrDirectionUnset
 	}
 
-	sortedSetKey := joinParam(sortedSetClient.sortedSetKeyFormat, param)
+	sortedSetKey, errKey := sortedSetClient.key(param)
+	if errKey != nil {
+		return nil, errKey
+	}
 
-	var result *redis.StringSliceCmd
-	if direction == Descending {
-		result = redisClient.ZRevRange(context.TODO(), sortedSetKey, 0, -1)
-	} else {
-		result = redisClient.ZRange(context.TODO(), sortedSetKey, 0, -1)
+	result, errRange := store.ZRange(ctx, sortedSetKey, 0, -1, direction == Descending)
+	if errRange != nil {
+		return nil, errRange
 	}
+	listRandIds := zMembers(result)
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	if fields != nil {
+		items, err := baseClient.GetManyProjected(listRandIds, fields)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(items); i < len(listRandIds); i++ {
+			metricsOrNoop(metrics).DanglingItem(sortedSetClient.sortedSetKeyFormat)
+		}
+
+		if len(listRandIds) > 0 {
+			store.Expire(ctx, sortedSetKey, SORTED_SET_TTL)
+		}
+
+		return items, nil
 	}
-	listRandIds := result.Val()
 
 	for i := 0; i < len(listRandIds); i++ {
 		if !extendTTL {
@@ -1063,16 +1618,21 @@ func FetchAll[T item.Blueprint](redisClient redis.UniversalClient, baseClient *B
 
 		item, err := baseClient.Get(listRandIds[i])
 		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				metricsOrNoop(metrics).DanglingItem(sortedSetClient.sortedSetKeyFormat)
+			} else {
+				failures = append(failures, &ItemError{RandId: listRandIds[i], Stage: StageRead, Err: err})
+			}
 			continue
 		}
 		items = append(items, item)
 	}
 
 	if extendTTL {
-		redisClient.Expire(context.TODO(), sortedSetKey, SORTED_SET_TTL)
+		store.Expire(ctx, sortedSetKey, SORTED_SET_TTL)
 	}
 
-	return items, nil
+	return items, partialError(failures)
 }
 
 func getItemScore[T item.Blueprint](item T, sortingReference string) (float64, error) {
@@ -1088,12 +1648,12 @@ func getItemScore[T item.Blueprint](item T, sortingReference string) (float64, e
 	}
 
 	if val.Kind() != reflect.Struct {
-		return 0, errors.New("getItemScore: item must be a struct or pointer to struct")
+		return 0, fmt.Errorf("%w: item must be a struct or pointer to struct", ErrUnsupportedSortField)
 	}
 
 	field := val.FieldByName(sortingReference)
 	if !field.IsValid() {
-		return 0, fmt.Errorf("getItemScore: field %s not found in item", sortingReference)
+		return 0, fmt.Errorf("%w: field %s not found in item", ErrUnsupportedSortField, sortingReference)
 	}
 
 	switch field.Type() {
@@ -1101,12 +1661,12 @@ func getItemScore[T item.Blueprint](item T, sortingReference string) (float64, e
 		return float64(field.Interface().(time.Time).UnixMilli()), nil
 	case reflect.TypeOf(&time.Time{}):
 		if field.IsNil() {
-			return 0, errors.New("getItemScore: time field is nil")
+			return 0, fmt.Errorf("%w: time field %s is nil", ErrUnsupportedSortField, sortingReference)
 		}
 		return float64(field.Interface().(*time.Time).UnixMilli()), nil
 	case reflect.TypeOf(int64(0)):
 		return float64(field.Interface().(int64)), nil
 	default:
-		return 0, fmt.Errorf("getItemScore: field %s is not a time.Time", sortingReference)
+		return 0, fmt.Errorf("%w: field %s is not a time.Time", ErrUnsupportedSortField, sortingReference)
 	}
 }
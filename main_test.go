@@ -1,10 +1,26 @@
 package pageflow
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lefalya/item"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
 
 func TestJoinParam(t *testing.T) {
 	key := "joinparams"
-	joinParam(key, nil)
+	joinParam(nil, key, nil)
 }
 
 func TestSetFirstPage(t *testing.T) {
@@ -13,6 +29,1896 @@ func TestSetFirstPage(t *testing.T) {
 		car string `json:"car" bson:"car"`
 	}
 	key := "voucher"
-	paginate := NewPaginate[Car](nil, nil, key, 10, Descending)
-	paginate.SetFirstPage(nil)
+	paginate := NewPaginateWithStore[Car](NewMemoryStore(), nil, key, 10, Descending, "")
+	err := paginate.SetFirstPage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isFirstPage, err := paginate.IsFirstPage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isFirstPage {
+		t.Error("first page marker must be set")
+	}
+}
+
+// Note is the item the MemoryStore tests store and page through.
+type Note struct {
+	*SQLItem
+	Body string `json:"body"`
+}
+
+func newNote(randId string, createdAt time.Time, body string) *Note {
+	note := &Note{SQLItem: &SQLItem{Foundation: &item.Foundation{}}, Body: body}
+	note.SetRandId(randId)
+	note.SetCreatedAt(createdAt)
+	return note
+}
+
+// newNoteFixture pages "note:%s" items through keyFormat on a fresh MemoryStore.
+func newNoteFixture(keyFormat string, itemPerPage int64, direction string) (*MemoryStore, *Base[*Note], *Paginate[*Note]) {
+	store := NewMemoryStore()
+	base := NewBaseWithStore[*Note](store, "note:%s")
+	return store, base, NewPaginateWithStore[*Note](store, base, keyFormat, itemPerPage, direction, "")
+}
+
+// seedNotes stores count notes an hour apart, the first at createdAt, and
+// ingests them under param. Note i is "note<i>" with body "<i>".
+func seedNotes(t *testing.T, base *Base[*Note], paginate *Paginate[*Note], param []string, count int, createdAt time.Time) []*Note {
+	t.Helper()
+	notes := make([]*Note, count)
+	for i := range notes {
+		notes[i] = newNote(fmt.Sprintf("note%d", i), createdAt.Add(time.Duration(i)*time.Hour), fmt.Sprint(i))
+		if err := base.Set(notes[i]); err != nil {
+			t.Fatal(err)
+		}
+		if err := paginate.IngestItem(notes[i], param, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return notes
+}
+
+func TestScoreBound(t *testing.T) {
+	cases := map[string]ScoreBound{
+		"-inf":  NegativeInfinity,
+		"+inf":  PositiveInfinity,
+		"1500":  Inclusive(1500),
+		"(1.25": Exclusive(1.25),
+	}
+
+	for expected, bound := range cases {
+		if bound.String() != expected {
+			t.Errorf("expected %s, got %s", expected, bound.String())
+		}
+	}
+}
+
+func TestFetchRange(t *testing.T) {
+	_, base, paginate := newNoteFixture("notes", 10, Descending)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedNotes(t, base, paginate, nil, 6, createdAt)
+	score := func(hour int) float64 {
+		return float64(createdAt.Add(time.Duration(hour) * time.Hour).UnixMilli())
+	}
+	bodies := func(page []*Note) string {
+		var result []string
+		for _, note := range page {
+			result = append(result, note.Body)
+		}
+		return strings.Join(result, ",")
+	}
+
+	var pages []string
+	cursor := ""
+	for {
+		page, next, err := paginate.FetchRange(nil, Inclusive(score(1)), Inclusive(score(4)), 2, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, bodies(page))
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if strings.Join(pages, "|") != "4,3|2,1|" {
+		t.Errorf("unexpected descending pages %q", pages)
+	}
+
+	sorted := NewSortedWithStore[*Note](paginate.store, base, "notes", Ascending, "")
+	page, cursor, err := sorted.FetchRange(nil, Exclusive(score(0)), Exclusive(score(5)), 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bodies(page) != "1,2,3" || cursor != "note3" {
+		t.Errorf("unexpected first ascending page %s, cursor %q", bodies(page), cursor)
+	}
+	page, cursor, err = sorted.FetchRange(nil, Exclusive(score(0)), Exclusive(score(5)), 3, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bodies(page) != "4" || cursor != "" {
+		t.Errorf("unexpected last ascending page %s, cursor %q", bodies(page), cursor)
+	}
+
+	page, _, err = sorted.FetchRange(nil, Inclusive(score(2)), PositiveInfinity, 0, "")
+	if err != nil || bodies(page) != "2,3,4,5" {
+		t.Errorf("a zero limit must return the whole window, got %s, %v", bodies(page), err)
+	}
+	if count, _ := sorted.CountRange(nil, Exclusive(score(2)), Inclusive(score(4))); count != 2 {
+		t.Errorf("expected 2 items in (2h, 4h], got %d", count)
+	}
+}
+
+func TestQueryResultKey(t *testing.T) {
+	type Post struct {
+		*SQLItem
+	}
+	query := NewQuery[Post](nil, nil, Intersect, 10, Descending, 0)
+
+	byAuthor := QuerySource{KeyFormat: "posts:author:%s", Param: []string{"alice"}}
+	byTag := QuerySource{KeyFormat: "posts:tag:%s", Param: []string{"go"}}
+
+	if query.ResultKey([]QuerySource{byAuthor, byTag}) != query.ResultKey([]QuerySource{byTag, byAuthor}) {
+		t.Error("result key must not depend on source order")
+	}
+
+	union := NewQuery[Post](nil, nil, Union, 10, Descending, 0)
+	if query.ResultKey([]QuerySource{byAuthor, byTag}) == union.ResultKey([]QuerySource{byAuthor, byTag}) {
+		t.Error("intersection and union must not share a result key")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	store, base, byAuthor := newNoteFixture("notes:author:%s", 10, Descending)
+	byTag := NewPaginateWithStore[*Note](store, base, "notes:tag:%s", 10, Descending, "")
+	notes := seedNotes(t, base, byAuthor, []string{"alice"}, 4, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	for _, note := range []*Note{notes[1], notes[3]} {
+		if err := byTag.IngestItem(note, []string{"go"}, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alice := QuerySource{KeyFormat: "notes:author:%s", Param: []string{"alice"}}
+	golang := QuerySource{KeyFormat: "notes:tag:%s", Param: []string{"go"}}
+	query := NewQueryWithStore[*Note](store, base, Intersect, 1, Descending, 0)
+
+	page, lastRandId, _, err := query.Fetch([]QuerySource{alice, golang}, nil, nil, nil)
+	if err != nil || len(page) != 1 || page[0].Body != "3" {
+		t.Fatalf("unexpected first page %v, %v", page, err)
+	}
+	page, _, _, err = query.Fetch([]QuerySource{alice, golang}, []string{lastRandId}, nil, nil)
+	if err != nil || len(page) != 1 || page[0].Body != "1" {
+		t.Fatalf("unexpected second page %v, %v", page, err)
+	}
+
+	union := NewQueryWithStore[*Note](store, base, Union, 10, Descending, 0)
+	if count, err := union.Count([]QuerySource{alice, golang}); err != nil || count != 4 {
+		t.Errorf("expected 4 items in the union, got %d, %v", count, err)
+	}
+
+	rust := QuerySource{KeyFormat: "notes:tag:%s", Param: []string{"rust"}}
+	empty := []QuerySource{alice, rust}
+	if count, err := query.Count(empty); err != nil || count != 0 {
+		t.Fatalf("expected an empty intersection, got %d, %v", count, err)
+	}
+	if exists, _ := store.Exists(context.TODO(), query.ResultKey(empty)+emptyResultSuffix); !exists {
+		t.Fatal("expected the empty result to be remembered")
+	}
+
+	// the cached empty result is served until it expires or is discarded
+	if err = byTag.IngestItem(notes[0], []string{"rust"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if page, _, _, err = query.Fetch(empty, nil, nil, nil); err != nil || len(page) != 0 {
+		t.Errorf("expected the cached empty page, got %v, %v", page, err)
+	}
+	if err = query.Discard(empty); err != nil {
+		t.Fatal(err)
+	}
+	if page, _, _, err = query.Fetch(empty, nil, nil, nil); err != nil || len(page) != 1 {
+		t.Errorf("expected the recomputed intersection, got %v, %v", page, err)
+	}
+
+	// on a cluster the result key joins the slot of a single untagged source
+	// and of hash tagged sources
+	store.SetSlotCheck(true)
+	if resultKey := query.ResultKey([]QuerySource{alice}); !strings.HasPrefix(resultKey, "query:{notes:author:alice}:") {
+		t.Errorf("expected %s tagged with its source key", resultKey)
+	}
+	if count, err := union.Count([]QuerySource{alice}); err != nil || count != 4 {
+		t.Errorf("expected a single untagged source to work on a cluster, got %d, %v", count, err)
+	}
+
+	backend := NewMemoryStore()
+	backend.SetSlotCheck(true)
+	namespaced, err := NewNamespacedStore(backend, "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenantBase := NewBaseWithStore[*Note](namespaced, "note:%s")
+	tenantAuthors := NewPaginateWithStore[*Note](namespaced, tenantBase, "notes:author:%s", 10, Descending, "")
+	seedNotes(t, tenantBase, tenantAuthors, []string{"alice"}, 2, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tenantUnion := NewQueryWithStore[*Note](namespaced, tenantBase, Union, 10, Descending, 0)
+	if count, err := tenantUnion.Count([]QuerySource{alice}); err != nil || count != 2 {
+		t.Errorf("expected the result key in the slot of the namespaced source, got %d, %v", count, err)
+	}
+	byAuthorTagged := NewPaginateWithStore[*Note](store, base, "notes:%s:posts", 10, Descending, "")
+	byAuthorTagged.SetHashTags(true)
+	pinnedTagged := NewPaginateWithStore[*Note](store, base, "notes:%s:pinned", 10, Descending, "")
+	pinnedTagged.SetHashTags(true)
+	for _, note := range notes {
+		if err = byAuthorTagged.IngestItem(note, []string{"alice"}, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = pinnedTagged.IngestItem(notes[2], []string{"alice"}, true); err != nil {
+		t.Fatal(err)
+	}
+	tagged := []QuerySource{
+		{KeyFormat: "notes:%s:posts", Param: []string{"alice"}, HashTags: true},
+		{KeyFormat: "notes:%s:pinned", Param: []string{"alice"}, HashTags: true},
+	}
+	if resultKey := query.ResultKey(tagged); KeySlot(resultKey) != KeySlot("{alice}") {
+		t.Errorf("expected %s in the slot of its sources", resultKey)
+	}
+	if page, _, _, err = query.Fetch(tagged, nil, nil, nil); err != nil || len(page) != 1 || page[0].Body != "2" {
+		t.Errorf("unexpected hash tagged intersection %v, %v", page, err)
+	}
+}
+
+func TestFanInCursor(t *testing.T) {
+	positions := map[string]fanInPosition{
+		"posts:author:alice": {Score: 1700000000000, Member: "abc"},
+		"posts:author:bob":   {Score: 1700000000500, Member: "xyz"},
+	}
+
+	cursor, err := encodeFanInCursor(positions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decodeFanInCursor(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded["posts:author:bob"] != positions["posts:author:bob"] {
+		t.Errorf("expected %v, got %v", positions["posts:author:bob"], decoded["posts:author:bob"])
+	}
+
+	if _, err := decodeFanInCursor("not a cursor"); err == nil {
+		t.Error("expected an error for a malformed cursor")
+	}
+
+	if !fanInBefore(Descending, fanInPosition{Score: 2}, fanInPosition{Score: 1}) {
+		t.Error("higher scores come first when descending")
+	}
+	if !fanInBefore(Ascending, fanInPosition{Score: 1, Member: "a"}, fanInPosition{Score: 1, Member: "b"}) {
+		t.Error("ties are ordered by member")
+	}
+}
+
+func TestFanIn(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, base, byAuthor := newNoteFixture("notes:author:%s", 10, Descending)
+	notes := seedNotes(t, base, byAuthor, []string{"alice"}, 4, createdAt)
+	bob := newNote("bob0", createdAt.Add(150*time.Minute), "b")
+	if err := base.Set(bob); err != nil {
+		t.Fatal(err)
+	}
+	// note1 and note3 are shared with alice
+	for _, note := range []*Note{notes[1], bob, notes[3]} {
+		if err := byAuthor.IngestItem(note, []string{"bob"}, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources := []QuerySource{
+		{KeyFormat: "notes:author:%s", Param: []string{"alice"}},
+		{KeyFormat: "notes:author:%s", Param: []string{"bob"}},
+	}
+	for _, serverMergeLimit := range []int64{FANIN_SERVER_MERGE_LIMIT, -1} {
+		for _, itemPerPage := range []int64{1, 2, 10} {
+			fanIn := NewFanInWithStore[*Note](store, base, itemPerPage, Descending)
+			fanIn.SetServerMergeLimit(serverMergeLimit)
+
+			var bodies []string
+			cursor := ""
+			for page := 0; page < 10; page++ {
+				items, next, err := fanIn.Fetch(sources, cursor)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, note := range items {
+					bodies = append(bodies, note.Body)
+				}
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if merged := strings.Join(bodies, ","); merged != "3,b,2,1,0" {
+				t.Errorf("merge limit %d, %d per page: expected each note once in order, got %s", serverMergeLimit, itemPerPage, merged)
+			}
+		}
+	}
+
+	// sources in different cluster slots are merged in process, not by ZUNION
+	store.SetSlotCheck(true)
+	fanIn := NewFanInWithStore[*Note](store, base, 10, Descending)
+	items, _, err := fanIn.Fetch(sources, "")
+	if err != nil || len(items) != 5 {
+		t.Errorf("expected the cross slot merge to return 5 notes, got %d, %v", len(items), err)
+	}
+
+	// slots are those of the keys a namespaced store actually sends
+	first, second := slotSharingParams("notes:author:", "tenant:notes:author:")
+	backend := NewMemoryStore()
+	namespaced, err := NewNamespacedStore(backend, "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenantBase := NewBaseWithStore[*Note](namespaced, "note:%s")
+	tenantAuthors := NewPaginateWithStore[*Note](namespaced, tenantBase, "notes:author:%s", 10, Descending, "")
+	seedNotes(t, tenantBase, tenantAuthors, []string{first}, 2, createdAt)
+	seedNotes(t, tenantBase, tenantAuthors, []string{second}, 3, createdAt)
+	backend.SetSlotCheck(true)
+	items, _, err = NewFanInWithStore[*Note](namespaced, tenantBase, 10, Descending).Fetch([]QuerySource{
+		{KeyFormat: "notes:author:%s", Param: []string{first}},
+		{KeyFormat: "notes:author:%s", Param: []string{second}},
+	}, "")
+	if err != nil || len(items) != 3 {
+		t.Errorf("expected the namespaced sources merged in process, got %d, %v", len(items), err)
+	}
+}
+
+// slotSharingParams returns two params that put prefix+param in the same
+// cluster slot, but not namespaced+param.
+func slotSharingParams(prefix string, namespaced string) (string, string) {
+	seen := make(map[int]string)
+	for i := 0; ; i++ {
+		param := fmt.Sprint("u", i)
+		slot := KeySlot(prefix + param)
+		other, ok := seen[slot]
+		if ok && KeySlot(namespaced+param) != KeySlot(namespaced+other) {
+			return other, param
+		}
+		seen[slot] = param
+	}
+}
+
+// failingStore fails the trims and deletes that touch a key in fail.
+type failingStore struct {
+	*MemoryStore
+	fail map[string]error
+}
+
+func (fs *failingStore) ZRemRangeByRankMany(ctx context.Context, keys []string, start int64, stop int64) []error {
+	errs := fs.MemoryStore.ZRemRangeByRankMany(ctx, keys, start, stop)
+	for i, key := range keys {
+		if err, ok := fs.fail[key]; ok {
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
+func (fs *failingStore) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err, ok := fs.fail[key]; ok {
+			return err
+		}
+	}
+	return fs.MemoryStore.Del(ctx, keys...)
+}
+
+func TestAddItemToMany(t *testing.T) {
+	errTrim := errors.New("trim failed")
+	errDel := errors.New("del failed")
+	store := &failingStore{MemoryStore: NewMemoryStore(), fail: map[string]error{
+		"notes:trimfail":       errTrim,
+		"notes:first:lastpage": errDel,
+	}}
+	base := NewBaseWithStore[*Note](store, "note:%s")
+	paginate := NewPaginateWithStore[*Note](store, base, "notes:%s", 2, Descending, "")
+	paginate.SetMaxLength(2)
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedNotes(t, base, paginate, []string{"first"}, 2, createdAt)
+	seedNotes(t, base, paginate, []string{"trimfail"}, 2, createdAt)
+	for _, note := range seedNotes(t, base, paginate, []string{"newer"}, 5, createdAt)[:3] {
+		if err := paginate.RemoveItem(note, []string{"newer"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paginate.SetFirstPage([]string{"first"})
+	paginate.SetLastPage([]string{"first"})
+	paginate.SetLastPage([]string{"trimfail"})
+	paginate.SetBlankPage([]string{"blank"})
+
+	fresh := newNote("fresh", createdAt.Add(2*time.Hour), "fresh")
+	failed, err := paginate.AddItemToMany(fresh, [][]string{{"trimfail"}, {"first"}, {"blank"}, {"skipped"}, {"newer"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 || failed["notes:trimfail"] != errTrim || failed["notes:first"] != errDel {
+		t.Errorf("expected each error on its own key, got %v", failed)
+	}
+
+	ctx := context.Background()
+	if _, err = store.ZScore(ctx, "notes:first", "fresh"); err != nil {
+		t.Error("expected the item added to the first page")
+	}
+	if count, _ := store.ZCard(ctx, "notes:first"); count != 2 {
+		t.Errorf("expected notes:first trimmed to 2, got %d", count)
+	}
+	if isFirstPage, _ := paginate.IsFirstPage([]string{"first"}); isFirstPage {
+		t.Error("expected the first page marker of a full first page cleared")
+	}
+	if isBlankPage, _ := paginate.IsBlankPage([]string{"blank"}); isBlankPage {
+		t.Error("expected the blank page marker cleared")
+	}
+	for _, key := range []string{"notes:blank", "notes:skipped", "notes:newer"} {
+		if _, err = store.ZScore(ctx, key, "fresh"); err == nil {
+			t.Errorf("expected %s skipped", key)
+		}
+	}
+
+	// an item added back through the fan-out is seedable there again
+	removed := seedNotes(t, base, paginate, []string{"readd"}, 2, createdAt)[1]
+	if err = paginate.RemoveItem(removed, []string{"readd"}); err != nil {
+		t.Fatal(err)
+	}
+	if failed, err = paginate.AddItemToMany(removed, [][]string{{"readd"}}); err != nil || len(failed) != 0 {
+		t.Fatalf("unexpected failures %v, %v", failed, err)
+	}
+	if err = paginate.IngestItem(removed, []string{"readd"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.ZScore(ctx, "notes:readd", removed.GetRandId()); err != nil {
+		t.Errorf("expected the tombstone lifted by the re-add, got %v", err)
+	}
+
+	// bound fields pick the sorted set from the item
+	bound := NewPaginateWithStore[*Note](store, base, "bodies:{Body}", 10, Descending, "")
+	bound.SetKeyTemplate(MustKeyTemplate("bodies:{Body}"))
+	if err = bound.BindFields(); err != nil {
+		t.Fatal(err)
+	}
+	if err = bound.IngestItem(newNote("old", createdAt, "x"), nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if failed, err = bound.AddItemToMany(newNote("new", createdAt.Add(time.Hour), "x"), [][]string{nil}); err != nil || len(failed) != 0 {
+		t.Fatalf("unexpected failures %v, %v", failed, err)
+	}
+	if _, err = store.ZScore(ctx, "bodies:x", "new"); err != nil {
+		t.Errorf("expected the item added to its bound sorted set, got %v", err)
+	}
+	if _, err = bound.AddItemToMany(newNote("new", createdAt, "x"), [][]string{{"y"}}); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected params the item does not belong to rejected, got %v", err)
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for direction, kept := range map[string]string{Descending: "note1,note2,note3", Ascending: "note0,note1,note2"} {
+		store, base, paginate := newNoteFixture("notes", 10, direction)
+		paginate.SetMaxLength(3)
+
+		notes := seedNotes(t, base, paginate, nil, 3, createdAt)
+		paginate.SetLastPage(nil)
+		if err := paginate.IngestItem(notes[1], nil, true); err != nil {
+			t.Fatal(err)
+		}
+		if isLastPage, _ := paginate.IsLastPage(nil); !isLastPage {
+			t.Errorf("%s: the last page marker must stay while nothing was trimmed", direction)
+		}
+
+		seedNotes(t, base, paginate, nil, 4, createdAt)
+		members, err := store.ZRange(context.TODO(), "notes", 0, -1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(zMembers(members), ","); got != kept {
+			t.Errorf("%s: expected %s kept, got %s", direction, kept, got)
+		}
+		if isLastPage, _ := paginate.IsLastPage(nil); isLastPage {
+			t.Errorf("%s: the last page marker must be cleared once the tail is trimmed", direction)
+		}
+	}
+
+	store, base, _ := newNoteFixture("notes", 10, Descending)
+	sorted := NewSortedWithStore[*Note](store, base, "sorted", Ascending, "")
+	sorted.SetMaxLength(2)
+	for i := 0; i < 3; i++ {
+		note := newNote(fmt.Sprintf("note%d", i), createdAt.Add(time.Duration(i)*time.Hour), "")
+		if err := sorted.IngestItem(note, nil, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if members, _ := store.ZRange(context.TODO(), "sorted", 0, -1, false); strings.Join(zMembers(members), ",") != "note0,note1" {
+		t.Errorf("expected Sorted to keep the head of the list, got %v", members)
+	}
+}
+
+func TestTieFraction(t *testing.T) {
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Second)
+
+	if tieFraction(earlier) <= tieFraction(later) {
+		t.Error("reaching a score earlier must yield a larger fraction")
+	}
+	if tieFraction(later) <= 0 || tieFraction(later) >= 1 {
+		t.Errorf("fraction out of range: %f", tieFraction(later))
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	store, base, _ := newNoteFixture("notes", 10, Descending)
+	notes := make([]*Note, 4)
+	for i := range notes {
+		notes[i] = newNote(fmt.Sprintf("note%d", i), time.Time{}, "")
+		if err := base.Set(notes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	board := NewLeaderboardWithStore[*Note](store, base, "board:%s", TieByFirstReached)
+	param := []string{"weekly"}
+	for _, increment := range []struct {
+		note  int
+		delta float64
+	}{{0, 10}, {1, 5}, {2, 10}, {3, 3}, {1, 5}} {
+		// tie-breaks are resolved to the millisecond
+		time.Sleep(2 * time.Millisecond)
+		if _, err := board.IncrementScore(param, notes[increment.note], increment.delta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := board.IncrementScore(param, notes[0], 0.5); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected a fractional increment to be rejected, got %v", err)
+	}
+
+	for i, expected := range []int64{0, 2, 1, 3} {
+		if rank, err := board.RankOf(param, notes[i]); err != nil || rank != expected {
+			t.Errorf("note%d: expected rank %d, got %d, %v", i, expected, rank, err)
+		}
+	}
+	if score, err := board.ScoreOf(param, notes[1]); err != nil || score != 10 {
+		t.Errorf("expected the tie-break stripped from the score, got %v, %v", score, err)
+	}
+
+	standings := func(rows []Standing[*Note]) string {
+		var result []string
+		for _, row := range rows {
+			result = append(result, fmt.Sprintf("%d:%s:%v", row.Rank, row.Item.GetRandId(), row.Score))
+		}
+		return strings.Join(result, ",")
+	}
+	top, err := board.TopN(param, 2)
+	if err != nil || standings(top) != "0:note0:10,1:note2:10" {
+		t.Errorf("unexpected top 2 %s, %v", standings(top), err)
+	}
+	around, err := board.AroundMe(param, notes[2], 1)
+	if err != nil || standings(around) != "0:note0:10,1:note2:10,2:note1:10" {
+		t.Errorf("unexpected standings around note2 %s, %v", standings(around), err)
+	}
+	if _, err = board.AroundMe([]string{"daily"}, notes[2], 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unranked item, got %v", err)
+	}
+
+	byMember := NewLeaderboardWithStore[*Note](store, base, "scores:%s", "")
+	byMember.IncrementScore(param, notes[1], 1.5)
+	if score, err := byMember.IncrementScore(param, notes[1], 1.5); err != nil || score != 3 {
+		t.Errorf("expected fractional increments to add up, got %v, %v", score, err)
+	}
+	byMember.IncrementScore(param, notes[0], 3)
+	top, err = byMember.TopN(param, 5)
+	if err != nil || standings(top) != "0:note1:3,1:note0:3" {
+		t.Errorf("expected ties ordered by member, highest first, got %s, %v", standings(top), err)
+	}
+}
+
+func TestHashEncoding(t *testing.T) {
+	type Author struct {
+		Name string `json:"name"`
+	}
+	type Post struct {
+		*SQLItem
+		Title     string    `json:"title"`
+		Likes     int64     `json:"likes"`
+		Author    Author    `json:"author"`
+		Tags      []string  `json:"tags"`
+		Published time.Time `json:"published"`
+	}
+
+	post := &Post{
+		SQLItem:   &SQLItem{Foundation: &item.Foundation{}},
+		Title:     "42",
+		Likes:     7,
+		Author:    Author{Name: "alice"},
+		Tags:      []string{"go", "redis"},
+		Published: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}
+	post.SetRandId(RandId())
+
+	values, err := encodeHash(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["likes"] != "7" || values["title"] != "42" {
+		t.Errorf("scalars must be stored bare, got %v and %v", values["likes"], values["title"])
+	}
+
+	decoded, err := decodeHash[*Post](values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Title != "42" || decoded.Likes != 7 || decoded.Author.Name != "alice" || len(decoded.Tags) != 2 {
+		t.Errorf("unexpected round trip: %+v", decoded)
+	}
+	if !decoded.Published.Equal(post.Published) || decoded.GetRandId() != post.GetRandId() {
+		t.Errorf("unexpected round trip: %+v", decoded)
+	}
+}
+
+func TestProjection(t *testing.T) {
+	type Post struct {
+		*SQLItem
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+
+	store := NewMemoryStore()
+	jsonBase := NewBaseWithStore[*Post](store, "post:%s")
+	jsonBase.SetSummaryFields("title")
+	hashBase := NewHashBaseWithStore[*Post](store, "hashpost:%s")
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for keyFormat, base := range map[string]*Base[*Post]{"posts": jsonBase, "hashposts": hashBase} {
+		paginate := NewPaginateWithStore[*Post](store, base, keyFormat, 10, Descending, "")
+		for i := 0; i < 2; i++ {
+			post := &Post{SQLItem: &SQLItem{Foundation: &item.Foundation{}}, Title: fmt.Sprint("title", i), Body: fmt.Sprint("body", i)}
+			post.SetRandId(fmt.Sprintf("post%d", i))
+			post.SetCreatedAt(createdAt.Add(time.Duration(i) * time.Hour))
+			if err := base.Set(post); err != nil {
+				t.Fatal(err)
+			}
+			if err := paginate.IngestItem(post, nil, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		page, lastRandId, _, err := paginate.FetchProjected(nil, nil, []string{"title"}, nil, nil)
+		if err != nil || len(page) != 2 || lastRandId != "post0" {
+			t.Fatalf("%s: unexpected page %v, %s, %v", base.itemKeyFormat, page, lastRandId, err)
+		}
+		if page[0].Title != "title1" || page[0].Body != "" {
+			t.Errorf("%s: expected only the title, got %+v", base.itemKeyFormat, page[0])
+		}
+
+		all, err := paginate.FetchAllProjected(nil, []string{"title", "body"})
+		if err != nil || len(all) != 2 || all[1].Title != "title0" || all[1].Body != "body0" {
+			t.Errorf("%s: expected both fields, got %v, %v", base.itemKeyFormat, all, err)
+		}
+
+		if _, _, _, err = paginate.FetchProjected(nil, nil, nil, nil, nil); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%s: expected an empty projection to be rejected, got %v", base.itemKeyFormat, err)
+		}
+	}
+
+	// "body" is not in the summary, so the JSON base reads the full items
+	if exists, _ := store.Exists(context.TODO(), "post:post0:summary"); !exists {
+		t.Error("expected the summary key written")
+	}
+	sorted := NewSortedWithStore[*Post](store, jsonBase, "posts", Ascending, "")
+	posts, err := sorted.FetchProjected(nil, []string{"body"})
+	if err != nil || len(posts) != 2 || posts[0].Body != "body0" || posts[0].Title != "title0" {
+		t.Errorf("expected full items when the summary does not cover the fields, got %v, %v", posts, err)
+	}
+
+	// a summary that expired is rebuilt from the full item
+	store.Del(context.TODO(), "post:post1:summary")
+	paginate := NewPaginateWithStore[*Post](store, jsonBase, "posts", 10, Descending, "")
+	page, _, _, err := paginate.FetchProjected(nil, nil, []string{"title"}, nil, nil)
+	if err != nil || len(page) != 2 || page[0].Title != "title1" || page[0].Body != "" {
+		t.Errorf("expected an item without summary projected from its full key, got %v, %v", page, err)
+	}
+	if exists, _ := store.Exists(context.TODO(), "post:post1:summary"); !exists {
+		t.Error("expected the missing summary written back")
+	}
+
+	// reads keep the summary alive as long as the item
+	store.Expire(context.TODO(), "post:post1:summary", time.Minute)
+	if _, err = jsonBase.Get("post1"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := store.TTL("post:post1:summary"); ttl <= time.Minute {
+		t.Errorf("expected Get to extend the summary, got %v", ttl)
+	}
+}
+
+func TestSkiplist(t *testing.T) {
+	sl := newSkiplist(1)
+	random := rand.New(rand.NewSource(2))
+
+	var members []ZMember
+	for i := 0; i < 200; i++ {
+		member := ZMember{Member: fmt.Sprintf("m%03d", i), Score: float64(random.Intn(50))}
+		members = append(members, member)
+		sl.insert(member.Member, member.Score)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].Member < members[j].Member
+	})
+
+	for i, member := range members {
+		if rank := sl.rank(member.Member, member.Score); rank != int64(i) {
+			t.Fatalf("expected rank %d for %s, got %d", i, member.Member, rank)
+		}
+		if node := sl.byRank(int64(i)); node == nil || node.member != member.Member {
+			t.Fatalf("unexpected node at rank %d", i)
+		}
+	}
+
+	if !sl.delete(members[10].Member, members[10].Score) {
+		t.Fatal("delete must find the member")
+	}
+	if sl.rank(members[10].Member, members[10].Score) != -1 {
+		t.Error("deleted member must not have a rank")
+	}
+	if sl.rank(members[11].Member, members[11].Score) != 10 {
+		t.Error("members after the deleted one must move up")
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.SetClock(func() time.Time { return now })
+
+	err := store.Set(context.TODO(), "voucher", "1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.TTL("voucher") != time.Minute {
+		t.Errorf("unexpected ttl %s", store.TTL("voucher"))
+	}
+
+	now = now.Add(2 * time.Minute)
+	_, err = store.Get(context.TODO(), "voucher")
+	if err != redis.Nil {
+		t.Errorf("expired key must be missing, got %v", err)
+	}
+
+	ctx := context.TODO()
+	store.ZAdd(ctx, "sorted", time.Minute, ZMember{Member: "a"})
+	store.ZAdd(ctx, "sorted", 0, ZMember{Member: "b"})
+	if store.TTL("sorted") != time.Minute {
+		t.Errorf("a zero ttl must leave the expiry of a sorted set untouched, got %s", store.TTL("sorted"))
+	}
+
+	replace := func(current string, exists bool) error { return nil }
+	for name, write := range map[string]func() error{
+		"Set":         func() error { return store.Set(ctx, "voucher", "2", 0) },
+		"CheckAndSet": func() error { return store.CheckAndSet(ctx, "voucher", replace, "2", 0) },
+	} {
+		store.Set(ctx, "voucher", "1", time.Minute)
+		if err = write(); err != nil {
+			t.Fatal(err)
+		}
+		if store.TTL("voucher") != 0 {
+			t.Errorf("%s with a zero ttl must clear the expiry, got %s", name, store.TTL("voucher"))
+		}
+	}
+}
+
+func TestMemoryStorePaginate(t *testing.T) {
+	_, base, paginate := newNoteFixture("notes", 2, Descending)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedNotes(t, base, paginate, nil, 5, createdAt)
+
+	page, lastRandId, position, err := paginate.Fetch(nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].Body != "4" || page[1].Body != "3" || position != firstPage {
+		t.Fatalf("unexpected first page %v at %s", page, position)
+	}
+
+	page, _, position, err = paginate.Fetch(nil, []string{lastRandId}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].Body != "2" || page[1].Body != "1" || position != middlePage {
+		t.Fatalf("unexpected second page %v at %s", page, position)
+	}
+
+	count, err := paginate.CountRange(nil, Inclusive(float64(createdAt.Add(time.Hour).UnixMilli())), PositiveInfinity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("expected 4 items in range, got %d", count)
+	}
+}
+
+func TestBaseCache(t *testing.T) {
+	store, reader, _ := newNoteFixture("notes", 10, Descending)
+	writer := NewBaseWithStore[*Note](store, "note:%s")
+	writer.SetPublishInvalidations(true)
+
+	err := reader.EnableCache(10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	note := newNote("note1", time.Time{}, "draft")
+	err = writer.Set(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		cached, errGet := reader.Get("note1")
+		if errGet != nil {
+			t.Fatal(errGet)
+		}
+		cached.Body = "mutated"
+	}
+	if stats := reader.CacheStats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	note.Body = "final"
+	err = writer.Set(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, err := reader.Get("note1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Body != "final" {
+		t.Errorf("cache must be invalidated by another writer, got %s", fresh.Body)
+	}
+
+	// a read that overlaps an invalidation must not cache what it read
+	read := reader.startCacheRead()
+	stale := *note
+	note.Body = "latest"
+	err = writer.Set(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read.remember("note:note1", &stale)
+	latest, err := reader.Get("note1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Body != "latest" {
+		t.Errorf("a read racing an invalidation must not be cached, got %s", latest.Body)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader.Get("note1")
+			reader.EnableCache(10, time.Minute)
+			reader.GetMany([]string{"note1"})
+			reader.DisableCache()
+		}()
+	}
+	wg.Wait()
+}
+
+type recordingMetrics struct {
+	NoopMetrics
+	dangling map[string]int
+	sizes    map[string]int64
+}
+
+func (rm *recordingMetrics) DanglingItem(keyFormat string) {
+	rm.dangling[keyFormat]++
+}
+
+func (rm *recordingMetrics) SortedSetSize(keyFormat string, size int64) {
+	rm.sizes[keyFormat] = size
+}
+
+func TestMetrics(t *testing.T) {
+	_, base, paginate := newNoteFixture("notes:%s", 10, Descending)
+
+	metrics := &recordingMetrics{dangling: map[string]int{}, sizes: map[string]int64{}}
+	paginate.SetMetrics(metrics)
+
+	for i := 0; i < 3; i++ {
+		note := newNote(fmt.Sprintf("note%d", i), time.Now(), "")
+		base.Set(note)
+		paginate.IngestItem(note, []string{"alice"}, true)
+
+		if i == 0 {
+			base.Del(note)
+		}
+	}
+
+	_, _, _, err := paginate.Fetch([]string{"alice"}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.dangling["notes:%s"] != 1 {
+		t.Errorf("expected one dangling item, got %v", metrics.dangling)
+	}
+	if metrics.sizes["notes:%s"] != 3 {
+		t.Errorf("expected size 3 labelled by key format, got %v", metrics.sizes)
+	}
+}
+
+type recordingTracer struct {
+	noop.TracerProvider
+	spans map[string]*recordingSpan
+}
+
+type recordingSpan struct {
+	noop.Span
+	attributes map[attribute.Key]attribute.Value
+	ended      bool
+}
+
+type spanRecorder struct {
+	noop.Tracer
+	spans map[string]*recordingSpan
+}
+
+func (rt *recordingTracer) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return &spanRecorder{spans: rt.spans}
+}
+
+func (rt *spanRecorder) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordingSpan{attributes: map[attribute.Key]attribute.Value{}}
+	config := trace.NewSpanStartConfig(options...)
+	span.SetAttributes(config.Attributes()...)
+	rt.spans[name] = span
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (rs *recordingSpan) SetAttributes(attributes ...attribute.KeyValue) {
+	for _, kv := range attributes {
+		rs.attributes[kv.Key] = kv.Value
+	}
+}
+
+func (rs *recordingSpan) End(options ...trace.SpanEndOption) {
+	rs.ended = true
+}
+
+// spanStore records the span in the context of every ZAdd.
+type spanStore struct {
+	*MemoryStore
+	zaddSpans []trace.Span
+}
+
+func (ss *spanStore) ZAdd(ctx context.Context, key string, ttl time.Duration, members ...ZMember) error {
+	ss.zaddSpans = append(ss.zaddSpans, trace.SpanFromContext(ctx))
+	return ss.MemoryStore.ZAdd(ctx, key, ttl, members...)
+}
+
+func TestTracing(t *testing.T) {
+	store := &spanStore{MemoryStore: NewMemoryStore()}
+	base := NewBaseWithStore[*Note](store, "note:%s")
+	paginate := NewPaginateWithStore[*Note](store, base, "notes:%s", 10, Ascending, "")
+
+	tracer := &recordingTracer{spans: map[string]*recordingSpan{}}
+	paginate.SetTracerProvider(tracer)
+
+	note := newNote("note1", time.Now(), "")
+	base.Set(note)
+	err := paginate.IngestItemContext(context.Background(), note, []string{"alice"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, _, _, err := paginate.Fetch([]string{"alice"}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ingest := tracer.spans["Paginate.IngestItem"]
+	if ingest == nil || !ingest.ended || !ingest.attributes[AttributeSeeded].AsBool() {
+		t.Fatalf("unexpected ingest span %+v", ingest)
+	}
+	// the store calls of an operation run under its span
+	if len(store.zaddSpans) != 1 || store.zaddSpans[0] != ingest {
+		t.Errorf("ZAdd ran outside of the ingest span: %v", store.zaddSpans)
+	}
+
+	fetch := tracer.spans["Paginate.Fetch"]
+	if fetch == nil || !fetch.ended {
+		t.Fatalf("unexpected fetch span %+v", fetch)
+	}
+	if fetch.attributes[AttributeKeyFormat].AsString() != "notes:%s" || fetch.attributes[AttributeItemsReturned].AsInt64() != int64(len(items)) {
+		t.Errorf("unexpected fetch attributes %v", fetch.attributes)
+	}
+}
+
+func TestPartialFetch(t *testing.T) {
+	store, base, paginate := newNoteFixture("notes", 10, Descending)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedNotes(t, base, paginate, nil, 3, createdAt)
+
+	// note1 is corrupt, note2 is gone: only the former is a failure.
+	ctx := context.Background()
+	if err := store.Set(ctx, "note:note1", "{", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Del(ctx, "note:note2"); err != nil {
+		t.Fatal(err)
+	}
+
+	page, _, _, err := paginate.Fetch(nil, nil, nil, nil)
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected a partial error, got %v", err)
+	}
+	if len(page) != 1 || page[0].Body != "0" {
+		t.Fatalf("unexpected page %v", page)
+	}
+	if len(partial.Errors) != 1 || partial.Errors[0].RandId != "note1" || partial.Errors[0].Stage != StageRead {
+		t.Errorf("unexpected failures %v", partial.Errors)
+	}
+
+	paginate.SetStrict(true)
+	page, _, _, err = paginate.Fetch(nil, nil, nil, nil)
+	var itemError *ItemError
+	if !errors.As(err, &itemError) || isPartial(err) || page != nil {
+		t.Errorf("expected strict fetch to fail without items, got %v and %v", page, err)
+	}
+
+	report := &SeedReport{}
+	note := newNote("note3", createdAt, "")
+	ingestErr := errors.New("ingest failed")
+	SeedItem(context.Background(), report, base, note, func(ctx context.Context, item *Note) error { return ingestErr })
+	if report.Err() == nil || report.Seeded != 0 || !errors.Is(report.Err(), ingestErr) {
+		t.Errorf("unexpected report %v", report)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	store := NewMemoryStore()
+	base := NewBaseWithStore[*SQLItem](store, "item:%s")
+	sortedSet := NewSortedSetWithStore[*SQLItem](store, "items")
+
+	_, err := base.Get("missing")
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, redis.Nil) {
+		t.Errorf("expected ErrNotFound without redis.Nil, got %v", err)
+	}
+
+	_, err = sortedSet.LowestScore(nil)
+	if !errors.Is(err, ErrEmptySortedSet) {
+		t.Errorf("expected ErrEmptySortedSet, got %v", err)
+	}
+
+	paginate := &Paginate[*SQLItem]{store: store, baseClient: base, sortedSetClient: sortedSet, itemPerPage: 10}
+	_, _, _, err = paginate.Fetch(nil, nil, nil, nil)
+	if !errors.Is(err, ErrDirectionUnset) {
+		t.Errorf("expected ErrDirectionUnset, got %v", err)
+	}
+
+	_, err = decodeFanInCursor("not a cursor")
+	if !errors.Is(err, ErrCursorInvalid) {
+		t.Errorf("expected ErrCursorInvalid, got %v", err)
+	}
+
+	_, err = getItemScore[*SQLItem](&SQLItem{Foundation: &item.Foundation{}}, "Missing")
+	if !errors.Is(err, ErrUnsupportedSortField) {
+		t.Errorf("expected ErrUnsupportedSortField, got %v", err)
+	}
+}
+
+func TestNamespacedStore(t *testing.T) {
+	shared := NewMemoryStore()
+	tenants := []string{"acme", "globex"}
+
+	for _, tenant := range tenants {
+		store, err := NewNamespacedStore(shared, tenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		base := NewBaseWithStore[*SQLItem](store, "item:%s")
+		paginate := NewPaginateWithStore[*SQLItem](store, base, "items", 10, Descending, "")
+
+		entry := &SQLItem{Foundation: &item.Foundation{}}
+		entry.SetRandId(tenant)
+		entry.SetCreatedAt(time.Now())
+		if err := base.Set(entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := paginate.IngestItem(entry, nil, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := paginate.SetFirstPage(nil); err != nil {
+			t.Fatal(err)
+		}
+
+		page, _, _, err := paginate.Fetch(nil, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 1 || page[0].GetRandId() != tenant {
+			t.Fatalf("tenant %s sees %v", tenant, page)
+		}
+	}
+
+	for _, key := range shared.Keys() {
+		if !strings.HasPrefix(key, "acme:") && !strings.HasPrefix(key, "globex:") {
+			t.Errorf("key %s is not namespaced", key)
+		}
+	}
+
+	deleted, err := DeleteNamespace(context.Background(), shared, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Errorf("expected 3 keys deleted, got %d", deleted)
+	}
+	for _, key := range shared.Keys() {
+		if strings.HasPrefix(key, "acme:") {
+			t.Errorf("key %s survived deletion", key)
+		}
+	}
+	if len(shared.Keys()) != 3 {
+		t.Errorf("expected the other tenant untouched, got %v", shared.Keys())
+	}
+
+	// "globex:x" under "acme" would be "acme:globex:x", the key "x" of
+	// namespace "acme:globex"; namespaces that nest or glob are refused
+	for _, namespace := range []string{"", "acme:globex", "acme*", "acme?", "acme[0]", "acme\\"} {
+		if _, err := NewNamespacedStore(shared, namespace); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("namespace %q: expected ErrInvalidParam, got %v", namespace, err)
+		}
+		if _, err := DeleteNamespace(context.Background(), shared, namespace); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("DeleteNamespace %q: expected ErrInvalidParam, got %v", namespace, err)
+		}
+	}
+	if len(shared.Keys()) != 3 {
+		t.Errorf("expected the refused deletes to leave %v", shared.Keys())
+	}
+}
+
+func TestKeyTemplate(t *testing.T) {
+	template, err := NewKeyTemplate("posts:{author}:{status}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := template.Placeholders(); len(names) != 2 || names[0] != "author" || names[1] != "status" {
+		t.Errorf("unexpected placeholders %v", names)
+	}
+
+	key, err := template.Render("a:b", "100%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "posts:a%3Ab:100%25" {
+		t.Errorf("unexpected key %s", key)
+	}
+
+	_, err = template.Render("alice")
+	if !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ErrInvalidParam for a missing param, got %v", err)
+	}
+
+	for _, pattern := range []string{"posts:{author", "posts:{}", "posts:{a}:{a}", "posts}:{a"} {
+		if _, err := NewKeyTemplate(pattern); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("expected %q to be rejected, got %v", pattern, err)
+		}
+	}
+
+	for _, format := range []string{"posts:%s:%s", "posts:%d:%d", "posts"} {
+		if _, err = joinParam(nil, format, []string{"alice"}); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%s: expected ErrInvalidParam for a param count that does not match, got %v", format, err)
+		}
+	}
+	key, err = joinParam(nil, "100%%:%s", []string{"%!s(MISSING)"})
+	if err != nil || key != "100%:%!s(MISSING)" {
+		t.Errorf("expected a param that looks like a fmt error accepted, got %q, %v", key, err)
+	}
+
+	// braces alone do not make a template, even once the same pattern was
+	// parsed as one elsewhere
+	MustKeyTemplate("{feed}:items")
+	key, err = joinParam(nil, "{feed}:items", nil)
+	if err != nil || key != "{feed}:items" {
+		t.Errorf("expected the static key {feed}:items, got %q, %v", key, err)
+	}
+
+	store := NewMemoryStore()
+	base := NewBaseWithStore[*SQLItem](store, "item:%s")
+	base.SetKeyTemplate(MustKeyTemplate("item:{id}"))
+	paginate := NewPaginateWithStore[*SQLItem](store, base, "posts:%s:%s", 10, Descending, "")
+	paginate.SetKeyTemplate(template)
+
+	entry := &SQLItem{Foundation: &item.Foundation{}}
+	entry.SetRandId("post1")
+	entry.SetCreatedAt(time.Now())
+	if err := base.Set(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := paginate.IngestItem(entry, []string{"alice", "draft"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := paginate.IngestItem(entry, []string{"alice"}, true); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ingest with a missing param to fail, got %v", err)
+	}
+
+	keys := store.Keys()
+	if len(keys) != 2 || keys[0] != "item:post1" || keys[1] != "posts:alice:draft" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func TestBindFields(t *testing.T) {
+	type Post struct {
+		*SQLItem
+		AuthorID string       `json:"authorId"`
+		Status   string       `json:"status"`
+		Body     []byte       `json:"body"`
+		Edited   *time.Time   `json:"edited"`
+		Tag      fmt.Stringer `json:"-"`
+		draft    bool
+	}
+
+	store := NewMemoryStore()
+	base := NewBaseWithStore[*Post](store, "post:%s")
+	paginate := NewPaginateWithStore[*Post](store, base, "posts:{AuthorID}:{Status}", 10, Descending, "")
+	paginate.SetKeyTemplate(MustKeyTemplate("posts:{AuthorID}:{Status}"))
+	if err := paginate.BindFields(); err != nil {
+		t.Fatal(err)
+	}
+
+	unbound := NewPaginateWithStore[*Post](store, base, "posts:{Body}", 10, Descending, "")
+	unbound.SetKeyTemplate(MustKeyTemplate("posts:{Body}"))
+	if err := unbound.BindFields(); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected a []byte field to be rejected, got %v", err)
+	}
+	unexported := NewPaginateWithStore[*Post](store, base, "posts:{draft}", 10, Descending, "")
+	unexported.SetKeyTemplate(MustKeyTemplate("posts:{draft}"))
+	if err := unexported.BindFields(); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected an unexported field to be rejected, got %v", err)
+	}
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newPost := func(randId string, status string) *Post {
+		post := &Post{SQLItem: &SQLItem{Foundation: &item.Foundation{}}, AuthorID: "alice", Status: status}
+		post.SetRandId(randId)
+		post.SetCreatedAt(createdAt)
+		return post
+	}
+
+	draft := newPost("post1", "draft")
+	if err := paginate.IngestItem(draft, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := paginate.IngestItem(newPost("post2", "published"), nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := paginate.IngestItem(draft, []string{"bob", "draft"}, true); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected params the item does not belong to to be rejected, got %v", err)
+	}
+
+	params, err := paginate.ParamsOf(draft)
+	if err != nil || !equalParams(params, []string{"alice", "draft"}) {
+		t.Errorf("unexpected params %v, %v", params, err)
+	}
+
+	// nil Stringers fail instead of panicking in String
+	for _, pattern := range []string{"edits:{Edited}", "tags:{Tag}"} {
+		nilable := NewPaginateWithStore[*Post](store, base, pattern, 10, Descending, "")
+		nilable.SetKeyTemplate(MustKeyTemplate(pattern))
+		if err := nilable.BindFields(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nilable.ParamsOf(draft); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%s: expected a nil field to be rejected, got %v", pattern, err)
+		}
+	}
+
+	published := newPost("post1", "published")
+	if err := paginate.MoveItem(draft, published); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if count, _ := store.ZCard(ctx, "posts:alice:draft"); count != 0 {
+		t.Errorf("expected the draft list to be empty, got %d", count)
+	}
+	if count, _ := store.ZCard(ctx, "posts:alice:published"); count != 2 {
+		t.Errorf("expected both posts in the published list, got %d", count)
+	}
+}
+
+func TestHashTags(t *testing.T) {
+	if slot := KeySlot("123456789"); slot != 12739 {
+		t.Errorf("expected slot 12739, got %d", slot)
+	}
+	if KeySlot("{user1000}.following") != KeySlot("{user1000}.followers") {
+		t.Error("expected keys sharing a hash tag to share a slot")
+	}
+
+	store := NewMemoryStore()
+	store.SetSlotCheck(true)
+	base := NewBaseWithStore[*SQLItem](store, "item:%s")
+	paginate := NewPaginateWithStore[*SQLItem](store, base, "posts:%s:%s", 10, Descending, "")
+	paginate.SetHashTags(true)
+
+	keys, err := paginate.Keys([]string{"alice", "draft"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys[0] != "posts:{alice:draft}" || !sameSlot(keys...) {
+		t.Errorf("expected colocated keys, got %v", keys)
+	}
+
+	entry := &SQLItem{Foundation: &item.Foundation{}}
+	entry.SetRandId("post1")
+	entry.SetCreatedAt(time.Now())
+	if err := base.Set(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := paginate.IngestItem(entry, []string{"alice", "draft"}, true); err != nil {
+		t.Fatal(err)
+	}
+	page, _, _, err := paginate.Fetch([]string{"alice", "draft"}, nil, nil, nil)
+	if err != nil || len(page) != 1 {
+		t.Fatalf("unexpected page %v, %v", page, err)
+	}
+
+	_, err = store.ZUnion(context.Background(), []string{"posts:{alice:draft}", "posts:{bob:draft}"})
+	if err == nil {
+		t.Error("expected a cross slot union to fail in slot check mode")
+	}
+
+	groups := groupBySlot([]string{"{a}1", "{b}1", "{a}2"})
+	if len(groups) != 2 || len(groups[0]) != 2 || groups[0][1] != 2 {
+		t.Errorf("unexpected slot groups %v", groups)
+	}
+}
+
+func TestInvalidationBus(t *testing.T) {
+	store, writer, writerPages := newNoteFixture("notes:%s", 2, Descending)
+	writer.SetPublishInvalidations(true)
+
+	reader := NewBaseWithStore[*Note](store, "note:%s")
+	readerPages := NewPaginateWithStore[*Note](store, reader, "notes:%s", 2, Descending, "")
+
+	var events []InvalidationEvent
+	subscription, err := readerPages.OnInvalidation(func(event InvalidationEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Close()
+
+	note := newNote("note1", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "draft")
+	err = writer.Set(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writerPages.IngestItem(note, []string{"alice"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writerPages.PurgePagination([]string{"alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []InvalidationEvent{
+		{Type: EventItemUpdated, KeyFormat: "note:%s", Key: "note:note1", RandId: "note1"},
+		{Type: EventItemDeleted, KeyFormat: "note:%s", Key: "note:note1", RandId: "note1"},
+		{Type: EventPaginationPurged, KeyFormat: "notes:%s", Key: "notes:alice", Param: []string{"alice"}},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i := range expected {
+		if events[i].Type != expected[i].Type || events[i].KeyFormat != expected[i].KeyFormat ||
+			events[i].Key != expected[i].Key || events[i].RandId != expected[i].RandId ||
+			!equalParams(events[i].Param, expected[i].Param) {
+			t.Errorf("event %d: expected %+v, got %+v", i, expected[i], events[i])
+		}
+	}
+
+	if event := decodeEvent("note:legacy"); event.Type != EventItemUpdated || event.Key != "note:legacy" {
+		t.Errorf("bare keys must decode as updates, got %+v", event)
+	}
+
+	// handlers subscribed concurrently all land on the one bus of the Base
+	concurrent := NewBaseWithStore[*Note](store, "note:%s")
+	var received atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			subscription, err := concurrent.OnInvalidation(func(event InvalidationEvent) {
+				received.Add(1)
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			t.Cleanup(func() { subscription.Close() })
+		}()
+	}
+	wg.Wait()
+	err = writer.Set(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.Load() != 8 {
+		t.Errorf("expected all 8 handlers called, got %d", received.Load())
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, base, paginate := newNoteFixture("notes", 2, Descending)
+	store.SetClock(func() time.Time { return now })
+
+	// by the time of the refresh note1 was deleted from the database and
+	// note2 written
+	replacement := newNote("note2", now, "replacement")
+	refreshes := make(chan []string, 2)
+	release := make(chan struct{})
+	err := paginate.SetStaleWhileRevalidate(time.Minute, func(ctx context.Context, param []string) error {
+		refreshes <- param
+		<-release
+		return paginate.IngestItemContext(ctx, replacement, param, true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = base.Set(replacement)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	note := newNote("note1", now, "cached")
+	err = base.Set(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = paginate.IngestItem(note, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = paginate.MarkFresh(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, err = paginate.Fetch(nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refreshes) != 0 {
+		t.Fatal("a fresh sorted set must not be refreshed")
+	}
+
+	now = now.Add(2 * time.Minute)
+	for i := 0; i < 2; i++ {
+		page, _, _, errFetch := paginate.Fetch(nil, nil, nil, nil)
+		if errFetch != nil {
+			t.Fatal(errFetch)
+		}
+		if len(page) != 1 || page[0].Body != "cached" {
+			t.Fatalf("stale page must be served, got %v", page)
+		}
+	}
+
+	<-refreshes
+	close(release)
+	for deadline := time.Now().Add(time.Second); ; {
+		fresh, _ := store.Exists(context.TODO(), "notes:fresh")
+		if fresh {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("refresh must mark the sorted set fresh")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(refreshes) != 0 {
+		t.Error("only one refresh may run at a time")
+	}
+	members, _ := store.ZRange(context.TODO(), "notes", 0, -1, false)
+	if len(members) != 1 || members[0].Member != "note2" {
+		t.Errorf("expected the refresh to rebuild the set, got %v", members)
+	}
+
+	if err = paginate.SetStaleWhileRevalidate(SORTED_SET_TTL, nil); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("expected ErrInvalidParam, got %v", err)
+	}
+}
+
+func TestTombstones(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, base, paginate := newNoteFixture("notes", 10, Descending)
+	store.SetClock(func() time.Time { return now })
+	ingest := func(ctx context.Context, note *Note) error {
+		return paginate.IngestItemContext(ctx, note, nil, true)
+	}
+
+	notes := []*Note{newNote("note0", now, ""), newNote("note1", now.Add(time.Hour), "")}
+
+	err := base.Del(notes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = paginate.RemoveItem(notes[1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := &SeedReport{}
+	for _, note := range notes {
+		err = SeedItem(context.Background(), report, base, note, ingest)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if report.Seeded != 0 {
+		t.Errorf("deleted and removed items must not be counted as seeded, seeded %d", report.Seeded)
+	}
+	if _, err = base.Get("note0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("a deleted item must not be seeded back, got %v", err)
+	}
+	if total := paginate.sortedSetClient.TotalItemOnSortedSet(nil); total != 0 {
+		t.Errorf("a removed item must not be seeded back, got %d items", total)
+	}
+
+	// a deletion landing between the seed's write and its ingest
+	racing := newNote("note2", now, "")
+	report = &SeedReport{}
+	err = SeedItem(context.Background(), report, base, racing, func(ctx context.Context, note *Note) error {
+		err := base.Del(note)
+		if err != nil {
+			return err
+		}
+		return ingest(ctx, note)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = base.Get("note2"); !errors.Is(err, ErrNotFound) || report.Seeded != 0 {
+		t.Errorf("an item deleted while being seeded must be taken back out, got %v and %d seeded", err, report.Seeded)
+	}
+	if total := paginate.sortedSetClient.TotalItemOnSortedSet(nil); total != 0 {
+		t.Errorf("an item deleted while being seeded must leave the sorted set, got %d items", total)
+	}
+
+	now = now.Add(TOMBSTONE_TTL)
+	for round := 0; round < 2; round++ {
+		report = &SeedReport{}
+		for _, note := range notes {
+			err = SeedItem(context.Background(), report, base, note, ingest)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if report.Seeded != 2 {
+			t.Errorf("round %d: expired tombstones and purges must not block seeding, seeded %d", round, report.Seeded)
+		}
+
+		err = paginate.PurgePagination(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompareAndSet(t *testing.T) {
+	store, base, _ := newNoteFixture("notes", 10, Descending)
+	for _, base := range []*Base[*Note]{base, NewHashBaseWithStore[*Note](store, "hashnote:%s")} {
+		note := newNote("note1", time.Time{}, "draft")
+		err := base.CompareAndSet(note, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = base.CompareAndSet(note, time.Time{}); !errors.Is(err, ErrConflict) {
+			t.Errorf("creating an existing item must conflict, got %v", err)
+		}
+
+		first, err := base.Get("note1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := base.Get("note1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		version := first.GetUpdatedAt()
+
+		first.Body = "first"
+		err = base.CompareAndSet(first, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		second.Body = "second"
+		err = base.CompareAndSet(second, version)
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("a stale write must conflict, got %v", err)
+		}
+		if !second.GetUpdatedAt().Equal(version) {
+			t.Error("a failed write must leave the version of the item untouched")
+		}
+
+		stored, err := base.Get("note1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Body != "first" || !stored.GetUpdatedAt().After(version) {
+			t.Errorf("expected the first write with a newer version, got %+v", stored)
+		}
+
+		missing := newNote("note2", time.Time{}, "")
+		if err = base.CompareAndSet(missing, version); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	}
+}
+
+func TestIngestIfNewer(t *testing.T) {
+	type Post struct {
+		*SQLItem
+		Likes int64 `json:"likes"`
+	}
+
+	store := NewMemoryStore()
+	base := NewBaseWithStore[*Post](store, "post:%s")
+	sorted := NewSortedWithStore[*Post](store, base, "posts", Descending, "Likes")
+
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newPost := func(randId string, likes int64, age time.Duration) *Post {
+		post := &Post{SQLItem: &SQLItem{Foundation: &item.Foundation{}}, Likes: likes}
+		post.SetRandId(randId)
+		post.SetUpdatedAt(updatedAt.Add(age))
+		return post
+	}
+
+	report := &SeedReport{}
+	for _, post := range []*Post{newPost("post0", 1, 0), newPost("post1", 5, 0)} {
+		err := SeedItem(context.Background(), report, base, post, func(ctx context.Context, post *Post) error {
+			return sorted.IngestItemContext(ctx, post, nil, true)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, event := range []struct {
+		post    *Post
+		applied bool
+	}{
+		{newPost("post1", 9, 2*time.Second), true},
+		{newPost("post1", 7, time.Second), false},
+		{newPost("post2", 3, 0), true},
+	} {
+		applied, err := sorted.IngestIfNewer(event.post, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied != event.applied {
+			t.Errorf("%s with %d likes: expected applied %v", event.post.GetRandId(), event.post.Likes, event.applied)
+		}
+	}
+
+	stored, err := base.Get("post1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	score, err := store.ZScore(context.TODO(), "posts", "post1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Likes != 9 || score != 9 {
+		t.Errorf("an older event must not overwrite a newer one, got %d likes scored %v", stored.Likes, score)
+	}
+	if _, err = store.ZScore(context.TODO(), "posts", "post2"); err != nil {
+		t.Errorf("a new item must be added, got %v", err)
+	}
+
+	drafts := NewSortedWithStore[*Post](store, base, "drafts", Descending, "Likes")
+	err = drafts.SetBlankPage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range []struct {
+		post  *Post
+		blank bool
+	}{
+		{newPost("post1", 4, time.Second), true},
+		{newPost("post1", 10, 3*time.Second), false},
+	} {
+		_, err = drafts.IngestIfNewer(event.post, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if blank, _ := drafts.IsBlankPage(nil); blank != event.blank {
+			t.Errorf("%d likes: expected the blank page marker %v, got %v", event.post.Likes, event.blank, blank)
+		}
+	}
+
+	err = base.Del(newPost("post0", 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := sorted.IngestIfNewer(newPost("post0", 2, time.Second), nil)
+	if err != nil || applied {
+		t.Errorf("a late event must not resurrect a deleted item, got %v, %v", applied, err)
+	}
+}
+
+func TestMembershipIndex(t *testing.T) {
+	type Post struct {
+		*SQLItem
+		Likes int64 `json:"likes"`
+	}
+
+	store := NewMemoryStore()
+	base := NewBaseWithStore[*Post](store, "post:%s")
+	sorted := NewSortedWithStore[*Post](store, base, "posts:%s", Descending, "Likes")
+	sorted.SetMembershipIndex(true)
+
+	post := &Post{SQLItem: &SQLItem{Foundation: &item.Foundation{}}, Likes: 1}
+	post.SetRandId("post0")
+	for _, param := range []string{"news", "sports"} {
+		err := sorted.IngestItem(post, []string{param}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	post.Likes = 8
+	err := sorted.UpdateItem(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"posts:news", "posts:sports"} {
+		score, err := store.ZScore(context.TODO(), key, "post0")
+		if err != nil || score != 8 {
+			t.Errorf("%s: expected the item re-scored to 8, got %v, %v", key, score, err)
+		}
+	}
+
+	err = sorted.DeleteItem(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"posts:news", "posts:sports"} {
+		if _, err := store.ZScore(context.TODO(), key, "post0"); err == nil {
+			t.Errorf("%s: expected the item removed", key)
+		}
+	}
+	if _, err = base.Get("post0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the item key deleted, got %v", err)
+	}
+	params, err := sorted.sortedSetClient.Memberships("post0")
+	if err != nil || len(params) != 0 {
+		t.Errorf("expected no memberships left, got %v, %v", params, err)
+	}
+
+	feed := NewPaginateWithStore[*Post](store, base, "feed:%s", 10, Descending, "Likes")
+	feed.SetMembershipIndex(true)
+	seeded := &Post{SQLItem: &SQLItem{Foundation: &item.Foundation{}}, Likes: 2}
+	seeded.SetRandId("post2")
+	for _, follower := range []string{"alice", "bob"} {
+		err = feed.IngestItem(seeded, []string{follower}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	fresh := &Post{SQLItem: &SQLItem{Foundation: &item.Foundation{}}, Likes: 9}
+	fresh.SetRandId("post1")
+	failed, err := feed.AddItemToMany(fresh, [][]string{{"alice"}, {"bob"}})
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("unexpected failures %v, %v", failed, err)
+	}
+	params, err = feed.sortedSetClient.Memberships("post1")
+	if err != nil || len(params) != 2 {
+		t.Errorf("expected AddItemToMany to index both sorted sets, got %v, %v", params, err)
+	}
+}
+
+func TestConsistency(t *testing.T) {
+	store := NewMemoryStore()
+	base := NewBaseWithStore[*SQLItem](store, "post:%s")
+	paginate := NewPaginateWithStore[*SQLItem](store, base, "posts:{author}", 10, Descending, "")
+	paginate.SetKeyTemplate(MustKeyTemplate("posts:{author}"))
+
+	for _, randId := range []string{"post0", "post1", "post2"} {
+		post := &SQLItem{Foundation: &item.Foundation{}}
+		post.SetRandId(randId)
+		post.SetCreatedAt(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		err := SeedItem(context.Background(), &SeedReport{}, base, post, func(ctx context.Context, post *SQLItem) error {
+			return paginate.IngestItemContext(ctx, post, []string{"alice"}, true)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	paginate.SetLastPage([]string{"alice"})
+	paginate.SetBlankPage([]string{"carol"})
+
+	// the item key of post1 expired, bob's set expired before its marker and
+	// dave's set was written without TTL
+	store.Del(context.TODO(), "post:post1")
+	paginate.SetFirstPage([]string{"bob"})
+	store.ZAdd(context.TODO(), "posts:dave", 0, ZMember{Score: 1, Member: "post2"})
+
+	report, err := paginate.Verify("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SortedSets != 2 {
+		t.Errorf("expected 2 sorted sets checked, got %d", report.SortedSets)
+	}
+	if dangling := report.DanglingMembers["posts:alice"]; len(dangling) != 1 || dangling[0] != "post1" {
+		t.Errorf("expected post1 dangling in posts:alice, got %v", report.DanglingMembers)
+	}
+	if len(report.OrphanedMarkers) != 1 || report.OrphanedMarkers[0] != "posts:bob:firstpage" {
+		t.Errorf("expected only the first page marker of bob orphaned, got %v", report.OrphanedMarkers)
+	}
+	if len(report.MissingTTL) != 1 || report.MissingTTL[0] != "posts:dave" {
+		t.Errorf("expected posts:dave without TTL, got %v", report.MissingTTL)
+	}
+	if isLastPage, _ := paginate.IsLastPage([]string{"alice"}); !isLastPage {
+		t.Error("Verify must not change anything")
+	}
+
+	// another format sharing the prefix, with items of its own Base, and a
+	// plain string key under the prefix must both be left alone
+	drafts := NewPaginateWithStore[*SQLItem](store, NewBaseWithStore[*SQLItem](store, "draft:%s"), "posts:{author}:drafts", 10, Descending, "")
+	drafts.SetKeyTemplate(MustKeyTemplate("posts:{author}:drafts"))
+	draft := &SQLItem{Foundation: &item.Foundation{}}
+	draft.SetRandId("draft0")
+	err = drafts.IngestItem(draft, []string{"alice"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(context.TODO(), "posts:config", "{}", time.Hour)
+
+	_, err = paginate.Repair("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.ZScore(context.TODO(), "posts:alice:drafts", "draft0"); err != nil {
+		t.Errorf("expected the sorted set of another format untouched, got %v", err)
+	}
+	if _, err = store.ZScore(context.TODO(), "posts:alice", "post1"); err == nil {
+		t.Error("expected the dangling member removed")
+	}
+	if isLastPage, _ := paginate.IsLastPage([]string{"alice"}); isLastPage {
+		t.Error("expected the last page marker cleared so the seeder refills the set")
+	}
+	if store.TTL("posts:dave") <= 0 {
+		t.Errorf("expected posts:dave to expire, got %v", store.TTL("posts:dave"))
+	}
+
+	report, err = paginate.Verify("")
+	if err != nil || !report.Consistent() {
+		t.Errorf("expected nothing left to repair, got %+v, %v", report, err)
+	}
 }
@@ -0,0 +1,237 @@
+package pageflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lefalya/item"
+)
+
+// SetMembershipIndex makes SetSortedSet and DeleteFromSortedSet keep the
+// membership index of every item up to date, at the cost of one more write
+// each. UpdateItem and DeleteItem need it; items added before it was enabled
+// are not indexed.
+//
+// The index of an item is a sorted set of the JSON encoded params of every
+// sorted set of this key format the item was added to. It has the item TTL so
+// it outlives those sets. Sets that are trimmed, deleted or expire leave stale
+// entries behind, which is why the index is only used to re-score existing
+// members or remove them.
+func (cr *SortedSet[T]) SetMembershipIndex(enabled bool) {
+	cr.memberships = enabled
+}
+
+func (cr *Paginate[T]) SetMembershipIndex(enabled bool) {
+	cr.sortedSetClient.SetMembershipIndex(enabled)
+}
+
+func (srtd *Sorted[T]) SetMembershipIndex(enabled bool) {
+	srtd.sortedSetClient.SetMembershipIndex(enabled)
+}
+
+func (cr *SortedSet[T]) membershipKey(randId string) string {
+	return "memberships:" + keyEscaper.Replace(cr.sortedSetKeyFormat) + ":" + keyEscaper.Replace(randId)
+}
+
+func encodeParams(param []string) (string, error) {
+	if param == nil {
+		param = []string{}
+	}
+	encoded, err := json.Marshal(param)
+	return string(encoded), err
+}
+
+func (cr *SortedSet[T]) track(ctx context.Context, param []string, randId string) error {
+	if !cr.memberships {
+		return nil
+	}
+
+	member, err := encodeParams(param)
+	if err != nil {
+		return err
+	}
+	return cr.store.ZAdd(ctx, cr.membershipKey(randId), INDIVIDUAL_KEY_TTL, ZMember{Member: member})
+}
+
+// trackAll is track for several sorted sets at once, with one write.
+func (cr *SortedSet[T]) trackAll(ctx context.Context, params [][]string, randId string) error {
+	if !cr.memberships || len(params) == 0 {
+		return nil
+	}
+
+	members := make([]ZMember, len(params))
+	for i, param := range params {
+		member, err := encodeParams(param)
+		if err != nil {
+			return err
+		}
+		members[i] = ZMember{Member: member}
+	}
+	return cr.store.ZAdd(ctx, cr.membershipKey(randId), INDIVIDUAL_KEY_TTL, members...)
+}
+
+func (cr *SortedSet[T]) untrack(ctx context.Context, param []string, randId string) error {
+	if !cr.memberships {
+		return nil
+	}
+
+	member, err := encodeParams(param)
+	if err != nil {
+		return err
+	}
+	return cr.store.ZRem(ctx, cr.membershipKey(randId), member)
+}
+
+// Memberships returns the params of every sorted set of this key format
+// randId was added to since the membership index was enabled.
+func (cr *SortedSet[T]) Memberships(randId string) ([][]string, error) {
+	return cr.listMemberships(context.TODO(), randId)
+}
+
+func (cr *SortedSet[T]) listMemberships(ctx context.Context, randId string) ([][]string, error) {
+	if !cr.memberships {
+		return nil, fmt.Errorf("%w: the membership index is disabled", ErrInvalidParam)
+	}
+
+	members, err := cr.store.ZRange(ctx, cr.membershipKey(randId), 0, -1, false)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([][]string, 0, len(members))
+	for _, member := range members {
+		var param []string
+		err = json.Unmarshal([]byte(member.Member), &param)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// updateItem stores item and re-scores it in every sorted set it is indexed
+// in, in one transaction.
+func updateItem[T item.Blueprint](ctx context.Context, base *Base[T], sortedSet *SortedSet[T], item T, score float64) error {
+	params, err := sortedSet.listMemberships(ctx, item.GetRandId())
+	if err != nil {
+		return err
+	}
+
+	zwrites := make([]ZWrite, 0, len(params))
+	for _, param := range params {
+		zwrite, err := sortedSet.zWrite(param, score, item, true)
+		if err != nil {
+			return err
+		}
+		zwrites = append(zwrites, zwrite)
+	}
+
+	key, errKey := base.itemKey(item.GetRandId())
+	if errKey != nil {
+		return errKey
+	}
+
+	always := func(stored T, exists bool) error { return nil }
+	for attempt := 0; attempt <= CONFLICT_RETRIES; attempt++ {
+		err = base.compareAndSet(ctx, key, item, always, zwrites...)
+		if !errors.Is(err, ErrConflict) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return base.invalidate(EventItemUpdated, item.GetRandId(), key)
+}
+
+// UpdateItem stores item and re-scores it in every sorted set of this
+// Paginate it was added to, e.g. after the field used as sortingReference
+// changed. Use MoveItem when bound fields changed.
+func (cr *Paginate[T]) UpdateItem(item T) error {
+	return cr.UpdateItemContext(context.Background(), item)
+}
+
+// UpdateItemContext is UpdateItem with its span and store calls under ctx.
+func (cr *Paginate[T]) UpdateItemContext(ctx context.Context, item T) error {
+	ctx, span := cr.StartSpan(ctx, "Paginate.UpdateItem")
+	score, err := getItemScore(item, cr.sortingReference)
+	if err == nil {
+		err = updateItem(ctx, cr.baseClient, cr.sortedSetClient, item, score)
+	}
+	EndSpan(span, err)
+	return err
+}
+
+// DeleteItem removes item from every sorted set of this Paginate it was added
+// to, then deletes it from the Base.
+func (cr *Paginate[T]) DeleteItem(item T) error {
+	return cr.DeleteItemContext(context.Background(), item)
+}
+
+// DeleteItemContext is DeleteItem with its span and store calls under ctx.
+func (cr *Paginate[T]) DeleteItemContext(ctx context.Context, item T) error {
+	ctx, span := cr.StartSpan(ctx, "Paginate.DeleteItem")
+	err := cr.deleteItem(ctx, item)
+	EndSpan(span, err)
+	return err
+}
+
+func (cr *Paginate[T]) deleteItem(ctx context.Context, item T) error {
+	params, err := cr.sortedSetClient.listMemberships(ctx, item.GetRandId())
+	if err != nil {
+		return err
+	}
+
+	for _, param := range params {
+		err = cr.removeFrom(ctx, item, param)
+		if err != nil {
+			return err
+		}
+	}
+
+	return cr.baseClient.del(ctx, item, true)
+}
+
+func (srtd *Sorted[T]) UpdateItem(item T) error {
+	return srtd.UpdateItemContext(context.Background(), item)
+}
+
+func (srtd *Sorted[T]) UpdateItemContext(ctx context.Context, item T) error {
+	ctx, span := srtd.StartSpan(ctx, "Sorted.UpdateItem")
+	score, err := getItemScore(item, srtd.sortingReference)
+	if err == nil {
+		err = updateItem(ctx, srtd.baseClient, srtd.sortedSetClient, item, score)
+	}
+	EndSpan(span, err)
+	return err
+}
+
+func (srtd *Sorted[T]) DeleteItem(item T) error {
+	return srtd.DeleteItemContext(context.Background(), item)
+}
+
+func (srtd *Sorted[T]) DeleteItemContext(ctx context.Context, item T) error {
+	ctx, span := srtd.StartSpan(ctx, "Sorted.DeleteItem")
+	err := srtd.deleteItem(ctx, item)
+	EndSpan(span, err)
+	return err
+}
+
+func (srtd *Sorted[T]) deleteItem(ctx context.Context, item T) error {
+	params, err := srtd.sortedSetClient.listMemberships(ctx, item.GetRandId())
+	if err != nil {
+		return err
+	}
+
+	for _, param := range params {
+		err = srtd.removeFrom(ctx, item, param)
+		if err != nil {
+			return err
+		}
+	}
+
+	return srtd.baseClient.del(ctx, item, true)
+}
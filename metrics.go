@@ -0,0 +1,112 @@
+package pageflow
+
+import (
+	"context"
+	"github.com/lefalya/item"
+	"time"
+)
+
+// Metrics receives the events pageflow components emit. Every method is
+// labelled with the key format of the component rather than the full key, so
+// the number of series stays bounded no matter how many params are used.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// CacheLookup is called for every read the in-process cache of a Base
+	// answers or misses.
+	CacheLookup(keyFormat string, hit bool)
+	// DanglingItem is called when a fetch skips an id whose item is gone.
+	DanglingItem(keyFormat string)
+	// Seeded is called after a seeder refilled one sorted set.
+	Seeded(keyFormat string, items int64, duration time.Duration)
+	// SortedSetSize is called with the size of a sorted set after an item was
+	// added to it.
+	SortedSetSize(keyFormat string, size int64)
+}
+
+// NoopMetrics discards every event. It is what components use until
+// SetMetrics is called.
+type NoopMetrics struct{}
+
+func (NoopMetrics) CacheLookup(keyFormat string, hit bool) {}
+
+func (NoopMetrics) DanglingItem(keyFormat string) {}
+
+func (NoopMetrics) Seeded(keyFormat string, items int64, duration time.Duration) {}
+
+func (NoopMetrics) SortedSetSize(keyFormat string, size int64) {}
+
+func metricsOrNoop(metrics Metrics) Metrics {
+	if metrics == nil {
+		return NoopMetrics{}
+	}
+	return metrics
+}
+
+func (cr *Base[T]) SetMetrics(metrics Metrics) {
+	cr.metrics = metrics
+}
+
+func (cr *Base[T]) GetMetrics() Metrics {
+	return metricsOrNoop(cr.metrics)
+}
+
+func (cr *Paginate[T]) SetMetrics(metrics Metrics) {
+	cr.metrics = metrics
+}
+
+func (cr *Paginate[T]) GetMetrics() Metrics {
+	return metricsOrNoop(cr.metrics)
+}
+
+// RecordSeed reports a seeding run of this Paginate. Seeders call it once
+// they are done writing.
+func (cr *Paginate[T]) RecordSeed(items int64, duration time.Duration) {
+	cr.GetMetrics().Seeded(cr.sortedSetClient.sortedSetKeyFormat, items, duration)
+}
+
+func (srtd *Sorted[T]) SetMetrics(metrics Metrics) {
+	srtd.metrics = metrics
+}
+
+func (srtd *Sorted[T]) GetMetrics() Metrics {
+	return metricsOrNoop(srtd.metrics)
+}
+
+func (srtd *Sorted[T]) RecordSeed(items int64, duration time.Duration) {
+	srtd.GetMetrics().Seeded(srtd.sortedSetClient.sortedSetKeyFormat, items, duration)
+}
+
+func (lb *Leaderboard[T]) SetMetrics(metrics Metrics) {
+	lb.metrics = metrics
+}
+
+func (lb *Leaderboard[T]) GetMetrics() Metrics {
+	return metricsOrNoop(lb.metrics)
+}
+
+func (q *Query[T]) SetMetrics(metrics Metrics) {
+	q.metrics = metrics
+}
+
+func (f *FanIn[T]) SetMetrics(metrics Metrics) {
+	f.metrics = metrics
+}
+
+// observeSize reports the size of the sorted set param selects, skipping the
+// extra ZCARD when nobody listens.
+func observeSize[T item.Blueprint](ctx context.Context, store Store, metrics Metrics, sortedSet *SortedSet[T], param []string) {
+	metrics = metricsOrNoop(metrics)
+	if _, ok := metrics.(NoopMetrics); ok {
+		return
+	}
+
+	key, err := sortedSet.key(param)
+	if err != nil {
+		return
+	}
+
+	size, err := store.ZCard(ctx, key)
+	if err == nil {
+		metrics.SortedSetSize(sortedSet.sortedSetKeyFormat, size)
+	}
+}
@@ -0,0 +1,99 @@
+package prometheus
+
+import (
+	"github.com/lefalya/pageflow"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Collector implements pageflow.Metrics on top of Prometheus. Every series is
+// labelled with key_format, the format string the component was built with,
+// never with a full key.
+type Collector struct {
+	cacheLookups  *prometheus.CounterVec
+	danglingItems *prometheus.CounterVec
+	seeds         *prometheus.CounterVec
+	seededItems   *prometheus.CounterVec
+	seedDuration  *prometheus.HistogramVec
+	sortedSetSize *prometheus.GaugeVec
+}
+
+var _ pageflow.Metrics = (*Collector)(nil)
+
+// NewCollector builds a Collector whose metric names start with namespace,
+// e.g. "pageflow". Register it with a prometheus.Registerer before use.
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Reads answered by the in-process cache of a Base, by result.",
+		}, []string{"key_format", "result"}),
+		danglingItems: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dangling_items_total",
+			Help:      "Ids skipped by a fetch because their item no longer exists.",
+		}, []string{"key_format"}),
+		seeds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "seeds_total",
+			Help:      "Seeding runs.",
+		}, []string{"key_format"}),
+		seededItems: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "seeded_items_total",
+			Help:      "Items written by seeding runs.",
+		}, []string{"key_format"}),
+		seedDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "seed_duration_seconds",
+			Help:      "Duration of seeding runs.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"key_format"}),
+		sortedSetSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sorted_set_size",
+			Help:      "Size of the most recently written sorted set.",
+		}, []string{"key_format"}),
+	}
+}
+
+func (c *Collector) Describe(descs chan<- *prometheus.Desc) {
+	c.cacheLookups.Describe(descs)
+	c.danglingItems.Describe(descs)
+	c.seeds.Describe(descs)
+	c.seededItems.Describe(descs)
+	c.seedDuration.Describe(descs)
+	c.sortedSetSize.Describe(descs)
+}
+
+func (c *Collector) Collect(metrics chan<- prometheus.Metric) {
+	c.cacheLookups.Collect(metrics)
+	c.danglingItems.Collect(metrics)
+	c.seeds.Collect(metrics)
+	c.seededItems.Collect(metrics)
+	c.seedDuration.Collect(metrics)
+	c.sortedSetSize.Collect(metrics)
+}
+
+func (c *Collector) CacheLookup(keyFormat string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	c.cacheLookups.WithLabelValues(keyFormat, result).Inc()
+}
+
+func (c *Collector) DanglingItem(keyFormat string) {
+	c.danglingItems.WithLabelValues(keyFormat).Inc()
+}
+
+func (c *Collector) Seeded(keyFormat string, items int64, duration time.Duration) {
+	c.seeds.WithLabelValues(keyFormat).Inc()
+	c.seededItems.WithLabelValues(keyFormat).Add(float64(items))
+	c.seedDuration.WithLabelValues(keyFormat).Observe(duration.Seconds())
+}
+
+func (c *Collector) SortedSetSize(keyFormat string, size int64) {
+	c.sortedSetSize.WithLabelValues(keyFormat).Set(float64(size))
+}
@@ -0,0 +1,96 @@
+package prometheus
+
+import (
+	"context"
+	"github.com/lefalya/item"
+	"github.com/lefalya/pageflow"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gather returns every sample of registry as "name{label=value,...}" mapped to
+// its value, or to its sample count for histograms.
+func gather(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			var labels []string
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetName()+"="+label.GetValue())
+			}
+			sort.Strings(labels)
+			name := family.GetName() + "{" + strings.Join(labels, ",") + "}"
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				samples[name] = metric.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				samples[name] = metric.GetGauge().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				samples[name] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return samples
+}
+
+func TestCollector(t *testing.T) {
+	collector := NewCollector("pageflow")
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatal(err)
+	}
+
+	collector.CacheLookup("note:%s", true)
+	collector.CacheLookup("note:%s", false)
+	collector.CacheLookup("note:%s", false)
+	collector.Seeded("notes:%s", 3, 2*time.Second)
+
+	store := pageflow.NewMemoryStore()
+	base := pageflow.NewBaseWithStore[*pageflow.SQLItem](store, "note:%s")
+	paginate := pageflow.NewPaginateWithStore[*pageflow.SQLItem](store, base, "notes:%s", 10, pageflow.Descending, "")
+	paginate.SetMetrics(collector)
+	for _, randId := range []string{"note0", "note1"} {
+		note := &pageflow.SQLItem{Foundation: &item.Foundation{}}
+		note.SetRandId(randId)
+		note.SetCreatedAt(time.Now())
+		if err := base.Set(note); err != nil {
+			t.Fatal(err)
+		}
+		if err := paginate.IngestItem(note, []string{"alice"}, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Del(context.Background(), "note:note0")
+	if _, _, _, err := paginate.Fetch([]string{"alice"}, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]float64{
+		`pageflow_cache_lookups_total{key_format=note:%s,result=hit}`:  1,
+		`pageflow_cache_lookups_total{key_format=note:%s,result=miss}`: 2,
+		`pageflow_seeds_total{key_format=notes:%s}`:                    1,
+		`pageflow_seeded_items_total{key_format=notes:%s}`:             3,
+		`pageflow_seed_duration_seconds{key_format=notes:%s}`:          1,
+		`pageflow_sorted_set_size{key_format=notes:%s}`:                2,
+		`pageflow_dangling_items_total{key_format=notes:%s}`:           1,
+	}
+	samples := gather(t, registry)
+	if len(samples) != len(expected) {
+		t.Errorf("expected %d series, got %v", len(expected), samples)
+	}
+	for name, value := range expected {
+		if samples[name] != value {
+			t.Errorf("%s: expected %v, got %v", name, value, samples[name])
+		}
+	}
+}
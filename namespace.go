@@ -0,0 +1,256 @@
+package pageflow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// namespacedStore prefixes every key and channel before handing it to the
+// wrapped Store, so the components built on top of it never see the prefix.
+type namespacedStore struct {
+	store  Store
+	prefix string
+}
+
+// NewNamespacedStore returns a Store that prefixes every key and pub/sub
+// channel with namespace and a colon: item keys, sorted sets, their
+// :firstpage, :lastpage and :blankpage markers, segment keys and query results
+// alike. Services or tenants sharing one Redis each pass their own namespace
+// to the *WithStore constructors and never collide.
+//
+// A namespace may not be empty or contain a colon, or "a" and "a:b" would
+// share keys, nor the glob characters *?[]\ used by DeleteNamespace.
+func NewNamespacedStore(store Store, namespace string) (Store, error) {
+	err := validateNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &namespacedStore{store: store, prefix: namespace + ":"}, nil
+}
+
+// DeleteNamespace removes every key written through
+// NewNamespacedStore(store, namespace), using SCAN so Redis is never blocked,
+// and returns how many keys it deleted. store must be the unprefixed Store.
+func DeleteNamespace(ctx context.Context, store Store, namespace string) (int64, error) {
+	err := validateNamespace(namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	err = store.ScanPrefix(ctx, namespace+":", func(keys []string) error {
+		err := store.Del(ctx, keys...)
+		if err != nil {
+			return err
+		}
+		deleted += int64(len(keys))
+		return nil
+	})
+	return deleted, err
+}
+
+func validateNamespace(namespace string) error {
+	if namespace == "" {
+		return fmt.Errorf("%w: empty namespace", ErrInvalidParam)
+	}
+	if strings.ContainsAny(namespace, ":*?[]\\") {
+		return fmt.Errorf("%w: namespace %q contains one of :*?[]\\", ErrInvalidParam, namespace)
+	}
+	return nil
+}
+
+func (ns *namespacedStore) key(key string) string {
+	return ns.prefix + key
+}
+
+func (ns *namespacedStore) keys(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = ns.prefix + key
+	}
+	return prefixed
+}
+
+func (ns *namespacedStore) BackendKey(key string) string {
+	return ns.store.BackendKey(ns.key(key))
+}
+
+func (ns *namespacedStore) Get(ctx context.Context, key string) (string, error) {
+	return ns.store.Get(ctx, ns.key(key))
+}
+
+func (ns *namespacedStore) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	return ns.store.MGet(ctx, ns.keys(keys)...)
+}
+
+func (ns *namespacedStore) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return ns.store.Set(ctx, ns.key(key), value, ttl)
+}
+
+func (ns *namespacedStore) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	return ns.store.SetNX(ctx, ns.key(key), value, ttl)
+}
+
+func (ns *namespacedStore) zwrites(zwrites []ZWrite) []ZWrite {
+	prefixed := make([]ZWrite, len(zwrites))
+	for i, zwrite := range zwrites {
+		zwrite.Key = ns.key(zwrite.Key)
+		prefixed[i] = zwrite
+	}
+	return prefixed
+}
+
+func (ns *namespacedStore) CheckAndSet(ctx context.Context, key string, check func(current string, exists bool) error, value string, ttl time.Duration, zwrites ...ZWrite) error {
+	return ns.store.CheckAndSet(ctx, ns.key(key), check, value, ttl, ns.zwrites(zwrites)...)
+}
+
+func (ns *namespacedStore) Del(ctx context.Context, keys ...string) error {
+	return ns.store.Del(ctx, ns.keys(keys)...)
+}
+
+func (ns *namespacedStore) Exists(ctx context.Context, key string) (bool, error) {
+	return ns.store.Exists(ctx, ns.key(key))
+}
+
+func (ns *namespacedStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return ns.store.Expire(ctx, ns.key(key), ttl)
+}
+
+func (ns *namespacedStore) ExpireMany(ctx context.Context, keys []string, ttl time.Duration) error {
+	return ns.store.ExpireMany(ctx, ns.keys(keys), ttl)
+}
+
+func (ns *namespacedStore) TTLMany(ctx context.Context, keys []string) ([]time.Duration, error) {
+	return ns.store.TTLMany(ctx, ns.keys(keys))
+}
+
+func (ns *namespacedStore) ScanPrefix(ctx context.Context, prefix string, batch func(keys []string) error) error {
+	return ns.store.ScanPrefix(ctx, ns.key(prefix), func(keys []string) error {
+		stripped := make([]string, len(keys))
+		for i, key := range keys {
+			stripped[i] = strings.TrimPrefix(key, ns.prefix)
+		}
+		return batch(stripped)
+	})
+}
+
+func (ns *namespacedStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return ns.store.HGetAll(ctx, ns.key(key))
+}
+
+func (ns *namespacedStore) HGetAllMany(ctx context.Context, keys []string) ([]map[string]string, error) {
+	return ns.store.HGetAllMany(ctx, ns.keys(keys))
+}
+
+func (ns *namespacedStore) HMGet(ctx context.Context, key string, fields ...string) ([]interface{}, error) {
+	return ns.store.HMGet(ctx, ns.key(key), fields...)
+}
+
+func (ns *namespacedStore) HMGetMany(ctx context.Context, keys []string, fields []string) ([][]interface{}, error) {
+	return ns.store.HMGetMany(ctx, ns.keys(keys), fields)
+}
+
+func (ns *namespacedStore) HReplace(ctx context.Context, key string, values map[string]string, ttl time.Duration) error {
+	return ns.store.HReplace(ctx, ns.key(key), values, ttl)
+}
+
+func (ns *namespacedStore) HCheckAndReplace(ctx context.Context, key string, check func(current map[string]string) error, values map[string]string, ttl time.Duration, zwrites ...ZWrite) error {
+	return ns.store.HCheckAndReplace(ctx, ns.key(key), check, values, ttl, ns.zwrites(zwrites)...)
+}
+
+func (ns *namespacedStore) HSetIfExists(ctx context.Context, key string, values map[string]string, ttl time.Duration) error {
+	return ns.store.HSetIfExists(ctx, ns.key(key), values, ttl)
+}
+
+func (ns *namespacedStore) HIncrByIfExists(ctx context.Context, key string, field string, delta int64, ttl time.Duration) (int64, error) {
+	return ns.store.HIncrByIfExists(ctx, ns.key(key), field, delta, ttl)
+}
+
+func (ns *namespacedStore) ZAdd(ctx context.Context, key string, ttl time.Duration, members ...ZMember) error {
+	return ns.store.ZAdd(ctx, ns.key(key), ttl, members...)
+}
+
+func (ns *namespacedStore) ZAddMany(ctx context.Context, keys []string, member ZMember, ttl time.Duration) []error {
+	return ns.store.ZAddMany(ctx, ns.keys(keys), member, ttl)
+}
+
+func (ns *namespacedStore) ZRem(ctx context.Context, key string, members ...string) error {
+	return ns.store.ZRem(ctx, ns.key(key), members...)
+}
+
+func (ns *namespacedStore) ZCard(ctx context.Context, key string) (int64, error) {
+	return ns.store.ZCard(ctx, ns.key(key))
+}
+
+func (ns *namespacedStore) ZCardMany(ctx context.Context, keys []string) ([]int64, error) {
+	return ns.store.ZCardMany(ctx, ns.keys(keys))
+}
+
+func (ns *namespacedStore) ZRange(ctx context.Context, key string, start int64, stop int64, rev bool) ([]ZMember, error) {
+	return ns.store.ZRange(ctx, ns.key(key), start, stop, rev)
+}
+
+func (ns *namespacedStore) ZRangeMany(ctx context.Context, keys []string, start int64, stop int64, rev bool) ([][]ZMember, error) {
+	return ns.store.ZRangeMany(ctx, ns.keys(keys), start, stop, rev)
+}
+
+func (ns *namespacedStore) ZRangeByScore(ctx context.Context, query ZRangeQuery) ([]ZMember, error) {
+	query.Key = ns.key(query.Key)
+	return ns.store.ZRangeByScore(ctx, query)
+}
+
+func (ns *namespacedStore) ZRangeByScoreMany(ctx context.Context, queries []ZRangeQuery) ([][]ZMember, error) {
+	prefixed := make([]ZRangeQuery, len(queries))
+	for i, query := range queries {
+		query.Key = ns.key(query.Key)
+		prefixed[i] = query
+	}
+	return ns.store.ZRangeByScoreMany(ctx, prefixed)
+}
+
+func (ns *namespacedStore) ZRank(ctx context.Context, key string, member string, rev bool) (int64, error) {
+	return ns.store.ZRank(ctx, ns.key(key), member, rev)
+}
+
+func (ns *namespacedStore) ZScore(ctx context.Context, key string, member string) (float64, error) {
+	return ns.store.ZScore(ctx, ns.key(key), member)
+}
+
+func (ns *namespacedStore) ZCount(ctx context.Context, key string, min ScoreBound, max ScoreBound) (int64, error) {
+	return ns.store.ZCount(ctx, ns.key(key), min, max)
+}
+
+func (ns *namespacedStore) ZRemRangeByRank(ctx context.Context, key string, start int64, stop int64) (int64, error) {
+	return ns.store.ZRemRangeByRank(ctx, ns.key(key), start, stop)
+}
+
+func (ns *namespacedStore) ZRemRangeByRankMany(ctx context.Context, keys []string, start int64, stop int64) []error {
+	return ns.store.ZRemRangeByRankMany(ctx, ns.keys(keys), start, stop)
+}
+
+func (ns *namespacedStore) ZIncrBy(ctx context.Context, key string, member string, delta float64, ttl time.Duration) (float64, error) {
+	return ns.store.ZIncrBy(ctx, ns.key(key), member, delta, ttl)
+}
+
+func (ns *namespacedStore) ZIncrWithTieBreak(ctx context.Context, key string, member string, delta float64, fraction float64, ttl time.Duration) (float64, error) {
+	return ns.store.ZIncrWithTieBreak(ctx, ns.key(key), member, delta, fraction, ttl)
+}
+
+func (ns *namespacedStore) ZStore(ctx context.Context, destination string, keys []string, union bool, ttl time.Duration) (int64, error) {
+	return ns.store.ZStore(ctx, ns.key(destination), ns.keys(keys), union, ttl)
+}
+
+func (ns *namespacedStore) ZUnion(ctx context.Context, keys []string) ([]ZMember, error) {
+	return ns.store.ZUnion(ctx, ns.keys(keys))
+}
+
+func (ns *namespacedStore) Publish(ctx context.Context, channel string, message string) error {
+	return ns.store.Publish(ctx, ns.key(channel), message)
+}
+
+func (ns *namespacedStore) Subscribe(ctx context.Context, channel string, handler func(message string)) (io.Closer, error) {
+	return ns.store.Subscribe(ctx, ns.key(channel), handler)
+}
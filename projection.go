@@ -0,0 +1,320 @@
+package pageflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SetSummaryFields makes a JSON Base keep a lighter copy of every item under
+// "<item key>:summary" holding only the given JSON fields. Projected fetches
+// read that key instead of the full item. Hash storage projects straight from
+// the hash and does not need it.
+func (cr *Base[T]) SetSummaryFields(fields ...string) {
+	cr.summaryFields = fields
+}
+
+func (cr *Base[T]) GetSummaryFields() []string {
+	return cr.summaryFields
+}
+
+func (cr *Base[T]) setSummary(key string, itemInByte []byte) error {
+	_, err := cr.writeSummary(key, itemInByte)
+	return err
+}
+
+// writeSummary stores the summary of the encoded item under key and returns
+// it.
+func (cr *Base[T]) writeSummary(key string, itemInByte []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(itemInByte, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := make(map[string]json.RawMessage, len(cr.summaryFields))
+	for _, name := range cr.summaryFields {
+		if value, ok := fields[name]; ok {
+			summary[name] = value
+		}
+	}
+
+	summaryInByte, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	errSetSummary := cr.store.Set(context.TODO(), key+":summary", string(summaryInByte), INDIVIDUAL_KEY_TTL)
+	if errSetSummary != nil {
+		return nil, errSetSummary
+	}
+
+	return summaryInByte, nil
+}
+
+// withSummary returns key together with its summary key, if the Base keeps
+// one, so both expire together.
+func (cr *Base[T]) withSummary(key string) []string {
+	if len(cr.summaryFields) == 0 {
+		return []string{key}
+	}
+	return []string{key, key + ":summary"}
+}
+
+// GetManyProjected hydrates only the named fields of each item, leaving the
+// others at their zero value. With JSON storage the fields must be covered by
+// SetSummaryFields, otherwise the full items are read instead.
+func (cr *Base[T]) GetManyProjected(params []string, fields []string) ([]T, error) {
+	found, err := cr.getManyProjectedById(params, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]T, 0, len(found))
+	for _, param := range params {
+		if item, ok := found[param]; ok {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (cr *Base[T]) getManyProjectedById(params []string, fields []string) (map[string]T, error) {
+	found := make(map[string]T, len(params))
+	if len(params) == 0 {
+		return found, nil
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: projection requires at least one field", ErrInvalidParam)
+	}
+
+	if cr.storage == StorageHash {
+		return cr.getManyHashFields(params, fields)
+	}
+
+	if !cr.summaryCovers(fields) {
+		return cr.getManyById(params)
+	}
+
+	keys := make([]string, len(params))
+	summaryKeys := make([]string, len(params))
+	for i, param := range params {
+		key, err := cr.itemKey(param)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		summaryKeys[i] = key + ":summary"
+	}
+
+	result, err := cr.store.MGet(context.TODO(), summaryKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	var touched []string
+	var unsummarized []string
+	for i, value := range result {
+		raw, ok := value.(string)
+		if !ok {
+			unsummarized = append(unsummarized, params[i])
+			continue
+		}
+
+		var item T
+		errorUnmarshal := json.Unmarshal([]byte(raw), &item)
+		if errorUnmarshal != nil {
+			unsummarized = append(unsummarized, params[i])
+			continue
+		}
+
+		found[params[i]] = item
+		touched = append(touched, keys[i], summaryKeys[i])
+	}
+
+	if len(touched) > 0 {
+		errExpire := cr.store.ExpireMany(context.TODO(), touched, INDIVIDUAL_KEY_TTL)
+		if errExpire != nil {
+			return nil, errExpire
+		}
+	}
+
+	if len(unsummarized) > 0 {
+		err = cr.resummarize(found, unsummarized)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return found, nil
+}
+
+// resummarize reads the items whose summary is missing, e.g. because it
+// expired or the item was written before SetSummaryFields, from their full
+// keys and writes their summary back. Items that are gone stay out of found.
+func (cr *Base[T]) resummarize(found map[string]T, params []string) error {
+	items, err := cr.getManyById(params)
+	if err != nil {
+		return err
+	}
+
+	for _, param := range params {
+		full, ok := items[param]
+		if !ok {
+			continue
+		}
+
+		key, errKey := cr.itemKey(param)
+		if errKey != nil {
+			return errKey
+		}
+
+		itemInByte, errMarshal := json.Marshal(full)
+		if errMarshal != nil {
+			return errMarshal
+		}
+
+		summaryInByte, errSummary := cr.writeSummary(key, itemInByte)
+		if errSummary != nil {
+			return errSummary
+		}
+
+		var item T
+		errorUnmarshal := json.Unmarshal(summaryInByte, &item)
+		if errorUnmarshal != nil {
+			return errorUnmarshal
+		}
+		found[param] = item
+	}
+	return nil
+}
+
+func (cr *Base[T]) getManyHashFields(params []string, fields []string) (map[string]T, error) {
+	found := make(map[string]T, len(params))
+
+	keys := make([]string, len(params))
+	for i, param := range params {
+		key, err := cr.itemKey(param)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+
+	results, err := cr.store.HMGetMany(context.TODO(), keys, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var touched []string
+	for i, result := range results {
+		values := make(map[string]string, len(fields))
+		for j, value := range result {
+			if raw, ok := value.(string); ok {
+				values[fields[j]] = raw
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		item, errDecode := decodeHash[T](values)
+		if errDecode != nil {
+			continue
+		}
+
+		found[params[i]] = item
+		touched = append(touched, keys[i])
+	}
+
+	if len(touched) > 0 {
+		errExpire := cr.store.ExpireMany(context.TODO(), touched, INDIVIDUAL_KEY_TTL)
+		if errExpire != nil {
+			return nil, errExpire
+		}
+	}
+
+	return found, nil
+}
+
+func (cr *Base[T]) summaryCovers(fields []string) bool {
+	if len(cr.summaryFields) == 0 {
+		return false
+	}
+
+	available := make(map[string]bool, len(cr.summaryFields))
+	for _, name := range cr.summaryFields {
+		available[name] = true
+	}
+
+	for _, name := range fields {
+		if !available[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// FetchProjected is Fetch reading only the named fields of each item. With
+// JSON storage, fields not covered by SetSummaryFields make it read the full
+// items, as GetManyProjected does.
+func (cr *Paginate[T]) FetchProjected(
+	param []string,
+	lastRandIds []string,
+	fields []string,
+	processorArgs []interface{},
+	processor func(item *T, args []interface{}),
+) ([]T, string, string, error) {
+	if cr.direction == "" {
+		return nil, "", "", ErrDirectionUnset
+	}
+	if len(fields) == 0 {
+		return nil, "", "", fmt.Errorf("%w: projection requires at least one field", ErrInvalidParam)
+	}
+
+	sortedSetKey, errKey := cr.sortedSetClient.key(param)
+	if errKey != nil {
+		return nil, "", "", errKey
+	}
+
+	items, validLastRandId, position, err := fetchPage(
+		context.TODO(),
+		cr.store,
+		cr.baseClient,
+		sortedSetKey,
+		cr.sortedSetClient.sortedSetKeyFormat,
+		cr.metrics,
+		cr.direction,
+		cr.itemPerPage,
+		lastRandIds,
+		fields,
+		processorArgs,
+		processor,
+	)
+	if err != nil {
+		return nil, validLastRandId, position, err
+	}
+
+	cr.store.Expire(context.TODO(), sortedSetKey, SORTED_SET_TTL)
+
+	return items, validLastRandId, position, nil
+}
+
+// FetchAllProjected is FetchAll reading only the named fields of each item,
+// with the same fallback to full items as FetchProjected.
+func (cr *Paginate[T]) FetchAllProjected(param []string, fields []string) ([]T, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: projection requires at least one field", ErrInvalidParam)
+	}
+	return fetchAll(context.TODO(), cr.store, cr.metrics, cr.baseClient, cr.sortedSetClient, param, cr.direction, fields)
+}
+
+// FetchProjected is Fetch reading only the named fields of each item, with
+// the same fallback to full items as Paginate.FetchProjected.
+func (srtd *Sorted[T]) FetchProjected(param []string, fields []string) ([]T, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: projection requires at least one field", ErrInvalidParam)
+	}
+	return fetchAll(context.TODO(), srtd.store, srtd.metrics, srtd.baseClient, srtd.sortedSetClient, param, srtd.direction, fields)
+}
@@ -0,0 +1,210 @@
+package pageflow
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/lefalya/item"
+	"github.com/redis/go-redis/v9"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	Intersect        = "Intersect"
+	Union            = "Union"
+	QUERY_RESULT_TTL = time.Minute
+	queryKeyPrefix   = "query:"
+
+	emptyResultSuffix = ":empty"
+)
+
+// QuerySource points at one existing sorted set, e.g. the key of a Paginate
+// for a given param combination. Template, when set, replaces KeyFormat.
+// Template and HashTags must match SetKeyTemplate and SetHashTags on the
+// component that owns the set.
+type QuerySource struct {
+	KeyFormat string
+	Template  *KeyTemplate
+	Param     []string
+	HashTags  bool
+}
+
+func (qs QuerySource) key() (string, error) {
+	keyFormat := qs.KeyFormat
+	if qs.Template != nil {
+		keyFormat = qs.Template.String()
+	}
+	if qs.HashTags {
+		return hashTaggedKey(qs.Template, keyFormat, qs.Param)
+	}
+	return joinParam(qs.Template, keyFormat, qs.Param)
+}
+
+// Query paginates over the intersection or union of several sorted sets. The
+// combined set is materialized once into a short-lived result key and reused
+// by every page request until resultTTL elapses.
+type Query[T item.Blueprint] struct {
+	store       Store
+	baseClient  *Base[T]
+	operation   string
+	itemPerPage int64
+	direction   string
+	resultTTL   time.Duration
+	metrics     Metrics
+}
+
+func (q *Query[T]) GetItemPerPage() int64 {
+	return q.itemPerPage
+}
+
+func (q *Query[T]) GetDirection() string {
+	return q.direction
+}
+
+// ResultKey returns the key the combined set for sources is stored under. The
+// order of sources does not matter. When the sources share a Redis Cluster
+// slot the result key is tagged to land in that slot too: a single source
+// gives "query:{<source key>}:<sha>", with the source key as the Store sends
+// it, namespace prefix included, and hash tagged sets of the same params
+// give "query:{<tag>}:<sha>". On a cluster, combining several sources
+// therefore needs them hash tagged into one slot, see QuerySource.HashTags;
+// sources spread over several slots fail with CROSSSLOT.
+func (q *Query[T]) ResultKey(sources []QuerySource) string {
+	keys := make([]string, len(sources))
+	for i, source := range sources {
+		// a source whose params do not fit its format is rejected by
+		// materialize before its result key is ever used
+		keys[i], _ = source.key()
+	}
+	sort.Strings(keys)
+
+	digest := sha1.Sum([]byte(q.operation + "\n" + strings.Join(keys, "\n")))
+	resultKey := queryKeyPrefix + hex.EncodeToString(digest[:])
+	sent := backendKeys(q.store, keys)
+	if len(sent) > 0 && sameSlot(sent...) {
+		if tag := slotTag(sent[0]); tag != "" {
+			resultKey = queryKeyPrefix + tag + ":" + hex.EncodeToString(digest[:])
+		}
+	}
+	return resultKey
+}
+
+func (q *Query[T]) materialize(sources []QuerySource) (string, error) {
+	if len(sources) == 0 {
+		return "", fmt.Errorf("%w: query requires at least one source", ErrInvalidParam)
+	}
+
+	keys := make([]string, len(sources))
+	for i, source := range sources {
+		key, err := source.key()
+		if err != nil {
+			return "", err
+		}
+		keys[i] = key
+	}
+
+	resultKey := q.ResultKey(sources)
+
+	// An empty result leaves no key behind, so it is remembered by a marker
+	// instead; reading the missing result key then yields empty pages.
+	for _, key := range []string{resultKey, resultKey + emptyResultSuffix} {
+		exists, err := q.store.Exists(context.TODO(), key)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return resultKey, nil
+		}
+	}
+
+	// Every source is scored by the same reference, so ZStore keeps the
+	// highest score instead of summing it once per matching set.
+	count, err := q.store.ZStore(context.TODO(), resultKey, keys, q.operation == Union, q.resultTTL)
+	if err != nil {
+		return "", err
+	}
+	if count == 0 {
+		err = q.store.Set(context.TODO(), resultKey+emptyResultSuffix, "1", q.resultTTL)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return resultKey, nil
+}
+
+func (q *Query[T]) Fetch(
+	sources []QuerySource,
+	lastRandIds []string,
+	processorArgs []interface{},
+	processor func(item *T, args []interface{}),
+) ([]T, string, string, error) {
+	if q.direction == "" {
+		return nil, "", "", ErrDirectionUnset
+	}
+
+	resultKey, err := q.materialize(sources)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return fetchPage(
+		context.TODO(),
+		q.store,
+		q.baseClient,
+		resultKey,
+		queryKeyPrefix,
+		q.metrics,
+		q.direction,
+		q.itemPerPage,
+		lastRandIds,
+		nil,
+		processorArgs,
+		processor,
+	)
+}
+
+func (q *Query[T]) Count(sources []QuerySource) (int64, error) {
+	resultKey, err := q.materialize(sources)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.store.ZCard(context.TODO(), resultKey)
+}
+
+// Discard drops the cached result so the next Fetch recomputes it.
+func (q *Query[T]) Discard(sources []QuerySource) error {
+	resultKey := q.ResultKey(sources)
+	return q.store.Del(context.TODO(), resultKey, resultKey+emptyResultSuffix)
+}
+
+func NewQuery[T item.Blueprint](client redis.UniversalClient, baseClient *Base[T], operation string, itemPerPage int64, direction string, resultTTL time.Duration) *Query[T] {
+	return NewQueryWithStore[T](NewRedisStore(client), baseClient, operation, itemPerPage, direction, resultTTL)
+}
+
+func NewQueryWithStore[T item.Blueprint](store Store, baseClient *Base[T], operation string, itemPerPage int64, direction string, resultTTL time.Duration) *Query[T] {
+	if operation != Intersect && operation != Union {
+		operation = Intersect
+	}
+
+	if direction != Ascending && direction != Descending {
+		direction = Descending
+	}
+
+	if resultTTL <= 0 {
+		resultTTL = QUERY_RESULT_TTL
+	}
+
+	return &Query[T]{
+		store:       store,
+		baseClient:  baseClient,
+		operation:   operation,
+		itemPerPage: itemPerPage,
+		direction:   direction,
+		resultTTL:   resultTTL,
+	}
+}
@@ -0,0 +1,186 @@
+package pageflow
+
+import (
+	"context"
+	"github.com/lefalya/item"
+	"github.com/redis/go-redis/v9"
+	"math"
+	"strconv"
+)
+
+// ScoreBound is one end of a score window used by FetchRange and CountRange.
+// Scores are whatever the sorted set was built with, e.g. UnixMilli for the
+// default createdAt reference.
+type ScoreBound struct {
+	Score     float64
+	Exclusive bool
+}
+
+var (
+	NegativeInfinity = ScoreBound{Score: math.Inf(-1)}
+	PositiveInfinity = ScoreBound{Score: math.Inf(1)}
+)
+
+func Inclusive(score float64) ScoreBound {
+	return ScoreBound{Score: score}
+}
+
+func Exclusive(score float64) ScoreBound {
+	return ScoreBound{Score: score, Exclusive: true}
+}
+
+// String renders the bound in the form expected by ZRANGE BYSCORE and ZCOUNT.
+func (b ScoreBound) String() string {
+	if math.IsInf(b.Score, -1) {
+		return "-inf"
+	}
+	if math.IsInf(b.Score, 1) {
+		return "+inf"
+	}
+
+	score := strconv.FormatFloat(b.Score, 'f', -1, 64)
+	if b.Exclusive {
+		return "(" + score
+	}
+	return score
+}
+
+// flip returns the bound that covers everything on the other side of b.
+func (b ScoreBound) flip() ScoreBound {
+	return ScoreBound{Score: b.Score, Exclusive: !b.Exclusive}
+}
+
+func (cr *Paginate[T]) FetchRange(param []string, min ScoreBound, max ScoreBound, limit int64, cursor string) ([]T, string, error) {
+	if limit <= 0 {
+		limit = cr.itemPerPage
+	}
+	return fetchRange(cr.store, cr.metrics, cr.baseClient, cr.sortedSetClient, param, cr.direction, min, max, limit, cursor)
+}
+
+func (cr *Paginate[T]) CountRange(param []string, min ScoreBound, max ScoreBound) (int64, error) {
+	return countRange(cr.store, cr.sortedSetClient, param, min, max)
+}
+
+func (srtd *Sorted[T]) FetchRange(param []string, min ScoreBound, max ScoreBound, limit int64, cursor string) ([]T, string, error) {
+	return fetchRange(srtd.store, srtd.metrics, srtd.baseClient, srtd.sortedSetClient, param, srtd.direction, min, max, limit, cursor)
+}
+
+func (srtd *Sorted[T]) CountRange(param []string, min ScoreBound, max ScoreBound) (int64, error) {
+	return countRange(srtd.store, srtd.sortedSetClient, param, min, max)
+}
+
+// fetchRange returns up to limit items whose score lies within [min, max],
+// ordered by direction. A limit of zero or less returns the whole window.
+// cursor is the last rand id of the previous page; the returned cursor is
+// empty once the window is exhausted.
+func fetchRange[T item.Blueprint](
+	store Store,
+	metrics Metrics,
+	baseClient *Base[T],
+	sortedSetClient *SortedSet[T],
+	param []string,
+	direction string,
+	min ScoreBound,
+	max ScoreBound,
+	limit int64,
+	cursor string,
+) ([]T, string, error) {
+	if direction == "" {
+		return nil, "", ErrDirectionUnset
+	}
+
+	sortedSetKey, errKey := sortedSetClient.key(param)
+	if errKey != nil {
+		return nil, "", errKey
+	}
+
+	var offset int64
+	if cursor != "" {
+		var err error
+		offset, err = rangeOffset(store, sortedSetKey, direction, min, max, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	result, err := store.ZRangeByScore(context.TODO(), ZRangeQuery{
+		Key:    sortedSetKey,
+		Min:    min,
+		Max:    max,
+		Rev:    direction == Descending,
+		Offset: offset,
+		Count:  limit,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	listRandIds := zMembers(result)
+
+	if len(listRandIds) > 0 {
+		store.Expire(context.TODO(), sortedSetKey, SORTED_SET_TTL)
+	}
+
+	items, err := baseClient.GetMany(listRandIds)
+	if err != nil {
+		return nil, "", err
+	}
+	for i := len(items); i < len(listRandIds); i++ {
+		metricsOrNoop(metrics).DanglingItem(sortedSetClient.sortedSetKeyFormat)
+	}
+
+	var nextCursor string
+	if limit > 0 && int64(len(listRandIds)) == limit {
+		nextCursor = listRandIds[len(listRandIds)-1]
+	}
+
+	return items, nextCursor, nil
+}
+
+// rangeOffset translates the cursor member into its position inside the score
+// window, so ties on the same score are paginated without gaps or repeats. An
+// unknown cursor restarts from the beginning of the window, the same way Fetch
+// treats stale lastRandIds.
+func rangeOffset(store Store, sortedSetKey string, direction string, min ScoreBound, max ScoreBound, cursor string) (int64, error) {
+	var outside int64
+
+	if direction == Descending {
+		if !math.IsInf(max.Score, 1) {
+			above, err := store.ZCount(context.TODO(), sortedSetKey, max.flip(), PositiveInfinity)
+			if err != nil {
+				return 0, err
+			}
+			outside = above
+		}
+	} else {
+		if !math.IsInf(min.Score, -1) {
+			below, err := store.ZCount(context.TODO(), sortedSetKey, NegativeInfinity, min.flip())
+			if err != nil {
+				return 0, err
+			}
+			outside = below
+		}
+	}
+
+	rank, err := store.ZRank(context.TODO(), sortedSetKey, cursor, direction == Descending)
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	offset := rank - outside + 1
+	if offset < 0 {
+		offset = 0
+	}
+	return offset, nil
+}
+
+func countRange[T item.Blueprint](store Store, sortedSetClient *SortedSet[T], param []string, min ScoreBound, max ScoreBound) (int64, error) {
+	sortedSetKey, errKey := sortedSetClient.key(param)
+	if errKey != nil {
+		return 0, errKey
+	}
+
+	return store.ZCount(context.TODO(), sortedSetKey, min, max)
+}
@@ -0,0 +1,164 @@
+package pageflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lefalya/item"
+)
+
+// Stages at which a single item can fail while being read or seeded.
+const (
+	StageRead   = "read"
+	StageDecode = "decode"
+	StageSet    = "set"
+	StageIngest = "ingest"
+	StageMarker = "marker"
+)
+
+// ItemError is the failure of one item. RandId is empty when the item could
+// not be decoded far enough to know it.
+type ItemError struct {
+	RandId string
+	Stage  string
+	Err    error
+}
+
+func (ie *ItemError) Error() string {
+	if ie.RandId == "" {
+		return fmt.Sprintf("%s: %v", ie.Stage, ie.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", ie.Stage, ie.RandId, ie.Err)
+}
+
+func (ie *ItemError) Unwrap() error {
+	return ie.Err
+}
+
+// PartialError is returned by Fetch and FetchAll, together with the items
+// that could be read, when some items failed for a reason other than being
+// missing. Missing items are expected and only reported to Metrics.
+type PartialError struct {
+	Errors []*ItemError
+}
+
+func (pe *PartialError) Error() string {
+	return fmt.Sprintf("%d items could not be read, first: %v", len(pe.Errors), pe.Errors[0])
+}
+
+func (pe *PartialError) Unwrap() []error {
+	errs := make([]error, len(pe.Errors))
+	for i, err := range pe.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+func partialError(failures []*ItemError) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return &PartialError{Errors: failures}
+}
+
+// isPartial reports whether err still comes with usable items.
+func isPartial(err error) bool {
+	var partial *PartialError
+	return errors.As(err, &partial)
+}
+
+// strictResult turns a partial result into the failure of its first item
+// when strict is set, so the caller gets no items at all.
+func strictResult(strict bool, err error) error {
+	var partial *PartialError
+	if strict && errors.As(err, &partial) {
+		return partial.Errors[0]
+	}
+	return err
+}
+
+// SetStrict makes Fetch and FetchAll fail on the first item that cannot be
+// read, instead of returning the rest together with a *PartialError.
+func (cr *Paginate[T]) SetStrict(strict bool) {
+	cr.strict = strict
+}
+
+func (srtd *Sorted[T]) SetStrict(strict bool) {
+	srtd.strict = strict
+}
+
+// SeedReport collects what one seeding run wrote and what it could not. It is
+// returned as the error of a seeder call when at least one failure occurred;
+// use errors.As to read it.
+type SeedReport struct {
+	Seeded int64
+	Errors []*ItemError
+}
+
+func (sr *SeedReport) Error() string {
+	return fmt.Sprintf("seeded %d items with %d failures, first: %v", sr.Seeded, len(sr.Errors), sr.Errors[0])
+}
+
+func (sr *SeedReport) Unwrap() []error {
+	errs := make([]error, len(sr.Errors))
+	for i, err := range sr.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Fail records a failure and returns it.
+func (sr *SeedReport) Fail(randId string, stage string, err error) error {
+	itemError := &ItemError{RandId: randId, Stage: stage, Err: err}
+	sr.Errors = append(sr.Errors, itemError)
+	return itemError
+}
+
+// Err returns the report as an error, or nil when nothing failed.
+func (sr *SeedReport) Err() error {
+	if len(sr.Errors) == 0 {
+		return nil
+	}
+	return sr
+}
+
+// SeedItem stores item in base and hands it to ingest, recording the first
+// failure in report. It returns that failure, or nil. Items deleted within the
+// tombstone TTL are taken back out and not counted as seeded; ingest must pass
+// ctx on to IngestItemContext for those removed from its sorted set to be
+// recognized.
+func SeedItem[T item.Blueprint](ctx context.Context, report *SeedReport, base *Base[T], item T, ingest func(ctx context.Context, item T) error) error {
+	state := &seedState{}
+	ctx = context.WithValue(ctx, seedKey{}, state)
+
+	err := base.Set(item)
+	if err != nil {
+		return report.Fail(item.GetRandId(), StageSet, err)
+	}
+
+	err = ingest(ctx, item)
+	if err != nil {
+		return report.Fail(item.GetRandId(), StageIngest, err)
+	}
+
+	// the ingest read the Base tombstone together with its sorted set one
+	if !state.checked {
+		state.deleted, err = base.isTombstoned(ctx, item.GetRandId())
+		if err != nil {
+			return report.Fail(item.GetRandId(), StageRead, err)
+		}
+	}
+	if state.deleted {
+		err = base.del(ctx, item, false)
+		if err != nil {
+			return report.Fail(item.GetRandId(), StageSet, err)
+		}
+		return nil
+	}
+	if state.skipped {
+		return nil
+	}
+
+	report.Seeded++
+	return nil
+}
@@ -0,0 +1,193 @@
+package pageflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// REVALIDATE_LOCK_TTL bounds one background refresh. While it runs no other
+// refresh of the same sorted set starts, in this process or any other; after a
+// failed one the lock is left to expire, so a failing source is retried at
+// most once per REVALIDATE_LOCK_TTL.
+const REVALIDATE_LOCK_TTL = time.Minute
+
+// Refresher reseeds the sorted set of param, usually by calling
+// SeedPartialContext or SeedAllContext of a seeder with ctx. It runs in the
+// background, so it should report its own errors.
+//
+// A refresh rebuilds the set: once the refresher returns, members it did not
+// seed under ctx are removed, so rows deleted from the database leave the
+// cache too. A refresh of the first page drops the rest of the list, which is
+// seeded again on demand like after SORTED_SET_TTL.
+type Refresher func(ctx context.Context, param []string) error
+
+type refreshKey struct{}
+
+// refreshed collects the members seeded into one sorted set during a refresh.
+type refreshed struct {
+	sortedSetKey string
+	mutex        sync.Mutex
+	members      map[string]bool
+}
+
+// recordRefreshed notes member as seeded when ctx belongs to a refresh of
+// sortedSetKey.
+func recordRefreshed(ctx context.Context, sortedSetKey string, member string) {
+	record, ok := ctx.Value(refreshKey{}).(*refreshed)
+	if !ok || record.sortedSetKey != sortedSetKey {
+		return
+	}
+
+	record.mutex.Lock()
+	record.members[member] = true
+	record.mutex.Unlock()
+}
+
+// revalidation keeps a sorted set fresh without making readers wait. A
+// ":fresh" marker is written next to the set with the soft TTL; once it has
+// expired but the set, with its hard SORTED_SET_TTL, still exists, reads are
+// served from the set and one refresh is started.
+type revalidation struct {
+	softTTL time.Duration
+	refresh Refresher
+}
+
+func newRevalidation(softTTL time.Duration, refresh Refresher) (*revalidation, error) {
+	if softTTL <= 0 || softTTL >= SORTED_SET_TTL {
+		return nil, fmt.Errorf("%w: soft ttl must be positive and below SORTED_SET_TTL", ErrInvalidParam)
+	}
+	if refresh == nil {
+		return nil, fmt.Errorf("%w: stale-while-revalidate requires a refresher", ErrInvalidParam)
+	}
+	return &revalidation{softTTL: softTTL, refresh: refresh}, nil
+}
+
+func (rv *revalidation) markFresh(ctx context.Context, store Store, sortedSetKey string) error {
+	if rv == nil {
+		return nil
+	}
+	return store.Set(ctx, sortedSetKey+":fresh", "1", rv.softTTL)
+}
+
+// check starts a refresh of sortedSetKey when the read found it cached but no
+// longer fresh. Reads never fail because of it.
+func (rv *revalidation) check(ctx context.Context, store Store, sortedSetKey string, param []string, cached bool) {
+	if rv == nil || !cached {
+		return
+	}
+
+	fresh, err := store.Exists(ctx, sortedSetKey+":fresh")
+	if err != nil || fresh {
+		return
+	}
+
+	lockKey := sortedSetKey + ":revalidating"
+	acquired, err := store.SetNX(ctx, lockKey, "1", REVALIDATE_LOCK_TTL)
+	if err != nil || !acquired {
+		return
+	}
+
+	go func() {
+		refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), REVALIDATE_LOCK_TTL)
+		defer cancel()
+
+		stale, err := store.ZRange(refreshCtx, sortedSetKey, 0, -1, false)
+		if err != nil {
+			return
+		}
+
+		record := &refreshed{sortedSetKey: sortedSetKey, members: make(map[string]bool)}
+		err = rv.refresh(context.WithValue(refreshCtx, refreshKey{}, record), param)
+		if err != nil {
+			return
+		}
+
+		err = rebuild(refreshCtx, store, sortedSetKey, stale, record)
+		if err != nil {
+			return
+		}
+
+		err = rv.markFresh(refreshCtx, store, sortedSetKey)
+		if err != nil {
+			return
+		}
+		store.Del(refreshCtx, lockKey)
+	}()
+}
+
+// rebuild removes the members the set held before the refresh that the
+// refresh did not seed again; members added by other writers meanwhile are
+// kept. A set that lost members may no longer reach the end of the list, so
+// :lastpage is cleared and the seeder fetches the tail again.
+func rebuild(ctx context.Context, store Store, sortedSetKey string, stale []ZMember, record *refreshed) error {
+	record.mutex.Lock()
+	var removed []string
+	for _, member := range stale {
+		if !record.members[member.Member] {
+			removed = append(removed, member.Member)
+		}
+	}
+	record.mutex.Unlock()
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	err := store.ZRem(ctx, sortedSetKey, removed...)
+	if err != nil {
+		return err
+	}
+	return store.Del(ctx, sortedSetKey+":lastpage")
+}
+
+// SetStaleWhileRevalidate serves sorted sets seeded more than softTTL ago as
+// they are and refreshes them in the background through refresh, instead of
+// letting them run into SORTED_SET_TTL and making the next reader reseed.
+// softTTL must be below SORTED_SET_TTL. Seeders mark the sets they seed as
+// fresh; sets that were never marked count as stale.
+func (cr *Paginate[T]) SetStaleWhileRevalidate(softTTL time.Duration, refresh Refresher) error {
+	rv, err := newRevalidation(softTTL, refresh)
+	if err != nil {
+		return err
+	}
+	cr.revalidation = rv
+	return nil
+}
+
+// MarkFresh restarts the soft TTL of the sorted set of param. Seeders call it
+// after seeding the first page; it does nothing unless
+// SetStaleWhileRevalidate was called.
+func (cr *Paginate[T]) MarkFresh(param []string) error {
+	if cr.revalidation == nil {
+		return nil
+	}
+
+	sortedSetKey, err := cr.sortedSetClient.key(param)
+	if err != nil {
+		return err
+	}
+	return cr.revalidation.markFresh(context.TODO(), cr.store, sortedSetKey)
+}
+
+func (srtd *Sorted[T]) SetStaleWhileRevalidate(softTTL time.Duration, refresh Refresher) error {
+	rv, err := newRevalidation(softTTL, refresh)
+	if err != nil {
+		return err
+	}
+	srtd.revalidation = rv
+	return nil
+}
+
+func (srtd *Sorted[T]) MarkFresh(param []string) error {
+	if srtd.revalidation == nil {
+		return nil
+	}
+
+	sortedSetKey, err := srtd.sortedSetClient.key(param)
+	if err != nil {
+		return err
+	}
+	return srtd.revalidation.markFresh(context.TODO(), srtd.store, sortedSetKey)
+}
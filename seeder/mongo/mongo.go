@@ -3,6 +3,7 @@ package mongo
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/lefalya/pageflow"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -11,9 +12,11 @@ import (
 	"time"
 )
 
+// These wrap the pageflow sentinel errors, so errors.Is(err, pageflow.ErrNotFound)
+// and friends hold for them too.
 var (
-	NoDatabaseProvided           = errors.New("No database provided!")
-	DocumentOrReferencesNotFound = errors.New("Document or References not found!")
+	NoDatabaseProvided           = fmt.Errorf("%w: No database provided!", pageflow.ErrInvalidParam)
+	DocumentOrReferencesNotFound = fmt.Errorf("%w: Document or References not found!", pageflow.ErrNotFound)
 )
 
 type PaginateMongoSeeder[T pageflow.MongoItemBlueprint] struct {
@@ -21,16 +24,28 @@ type PaginateMongoSeeder[T pageflow.MongoItemBlueprint] struct {
 	baseClient       *pageflow.Base[T]
 	paginationClient *pageflow.Paginate[T]
 	scoringField     string
+	strict           bool
+}
+
+// SetStrict makes seeding stop at the first failed document instead of
+// skipping it. Either way failures are returned as a *pageflow.SeedReport.
+func (m *PaginateMongoSeeder[T]) SetStrict(strict bool) {
+	m.strict = strict
 }
 
 func (m *PaginateMongoSeeder[T]) FindOne(key string, value string, initItem func() T) (T, error) {
+	return m.FindOneContext(context.Background(), key, value, initItem)
+}
+
+// FindOneContext is FindOne with its query under ctx.
+func (m *PaginateMongoSeeder[T]) FindOneContext(ctx context.Context, key string, value string, initItem func() T) (T, error) {
 	mongoItem := initItem()
 	if m.coll == nil {
 		return mongoItem, NoDatabaseProvided
 	}
 
 	filter := bson.D{{key, value}}
-	err := m.coll.FindOne(context.TODO(), filter).Decode(&mongoItem)
+	err := m.coll.FindOne(ctx, filter).Decode(&mongoItem)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return mongoItem, DocumentOrReferencesNotFound
@@ -42,15 +57,43 @@ func (m *PaginateMongoSeeder[T]) FindOne(key string, value string, initItem func
 }
 
 func (m *PaginateMongoSeeder[T]) SeedOne(key string, value string, initItem func() T) error {
-	item, err := m.FindOne(key, value, initItem)
-	if err != nil {
-		return err
+	return m.SeedOneContext(context.Background(), key, value, initItem)
+}
+
+// SeedOneContext is SeedOne with its span and query under ctx.
+func (m *PaginateMongoSeeder[T]) SeedOneContext(ctx context.Context, key string, value string, initItem func() T) error {
+	ctx, span := m.paginationClient.StartSpan(ctx, "PaginateMongoSeeder.SeedOne")
+	span.SetAttributes(pageflow.AttributeSeeded.Bool(true))
+
+	item, err := m.FindOneContext(ctx, key, value, initItem)
+	if err == nil {
+		err = m.baseClient.Set(item)
 	}
 
-	return m.baseClient.Set(item)
+	pageflow.EndSpan(span, err)
+	return err
 }
 
 func (m *PaginateMongoSeeder[T]) SeedPartial(subtraction int64, validLastRandId string, query bson.D, paginateParams []string, initItem func() T) error {
+	return m.SeedPartialContext(context.Background(), subtraction, validLastRandId, query, paginateParams, initItem)
+}
+
+// SeedPartialContext is SeedPartial with its span, queries and ingests under
+// ctx.
+func (m *PaginateMongoSeeder[T]) SeedPartialContext(ctx context.Context, subtraction int64, validLastRandId string, query bson.D, paginateParams []string, initItem func() T) error {
+	ctx, span := m.paginationClient.StartSpan(ctx, "PaginateMongoSeeder.SeedPartial")
+	span.SetAttributes(pageflow.AttributeSeeded.Bool(true))
+
+	items, err := m.seedPartial(ctx, subtraction, validLastRandId, query, paginateParams, initItem)
+
+	span.SetAttributes(pageflow.AttributeItemsReturned.Int64(items))
+	pageflow.EndSpan(span, err)
+	return err
+}
+
+func (m *PaginateMongoSeeder[T]) seedPartial(ctx context.Context, subtraction int64, validLastRandId string, query bson.D, paginateParams []string, initItem func() T) (int64, error) {
+	seedStart := time.Now()
+
 	var cursor *mongo.Cursor
 	var reference T
 	var withReference bool
@@ -82,12 +125,12 @@ func (m *PaginateMongoSeeder[T]) SeedPartial(subtraction int64, validLastRandId
 	}
 
 	if validLastRandId != "" {
-		reference, err = m.FindOne("randid", validLastRandId, initItem)
+		reference, err = m.FindOneContext(ctx, "randid", validLastRandId, initItem)
 		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				return DocumentOrReferencesNotFound
+			if errors.Is(err, DocumentOrReferencesNotFound) {
+				return 0, fmt.Errorf("%w: %w", pageflow.ErrCursorInvalid, err)
 			}
-			return err
+			return 0, err
 		} else {
 			withReference = true
 		}
@@ -128,56 +171,123 @@ func (m *PaginateMongoSeeder[T]) SeedPartial(subtraction int64, validLastRandId
 		findOptions.SetLimit(m.paginationClient.GetItemPerPage())
 	}
 
-	cursor, err = m.coll.Find(context.TODO(), filter, findOptions)
+	cursor, err = m.coll.Find(ctx, filter, findOptions)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(ctx)
 
 	var counterLoop int64
 	counterLoop = 0
-	for cursor.Next(context.TODO()) {
+	report := &pageflow.SeedReport{}
+	ingest := func(ctx context.Context, item T) error {
+		return m.paginationClient.IngestItemContext(ctx, item, paginateParams, true)
+	}
+	for cursor.Next(ctx) {
 		item := initItem()
 		errorDecode = cursor.Decode(&item)
 		if errorDecode != nil {
+			report.Fail("", pageflow.StageDecode, errorDecode)
+			if m.strict {
+				return report.Seeded, report
+			}
 			continue
 		}
-
-		m.baseClient.Set(item)
-		m.paginationClient.IngestItem(item, paginateParams, true)
 		counterLoop++
+
+		err = pageflow.SeedItem(ctx, report, m.baseClient, item, ingest)
+		if err != nil && m.strict {
+			return report.Seeded, report
+		}
+
+		// with bound fields a page without params takes those of its first item
+		if err == nil && paginateParams == nil {
+			paginateParams, _ = m.paginationClient.ParamsOf(item)
+		}
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		report.Fail("", pageflow.StageRead, err)
+		return report.Seeded, report
 	}
 
 	if firstPage && counterLoop == 0 {
-		m.paginationClient.SetBlankPage(paginateParams)
+		err = m.paginationClient.SetBlankPage(paginateParams)
 	} else if firstPage && counterLoop > 0 && counterLoop < m.paginationClient.GetItemPerPage() {
-		m.paginationClient.SetFirstPage(paginateParams)
+		err = m.paginationClient.SetFirstPage(paginateParams)
 	} else if validLastRandId != "" && subtraction+counterLoop < m.paginationClient.GetItemPerPage() {
-		m.paginationClient.SetLastPage(paginateParams)
+		err = m.paginationClient.SetLastPage(paginateParams)
+	}
+	if err == nil && firstPage {
+		err = m.paginationClient.MarkFresh(paginateParams)
+	}
+	if err != nil {
+		report.Fail("", pageflow.StageMarker, err)
 	}
 
-	return nil
+	m.paginationClient.RecordSeed(report.Seeded, time.Since(seedStart))
+	return report.Seeded, report.Err()
 }
 
 func (m *PaginateMongoSeeder[T]) SeedAll(query bson.D, listParam []string, initItem func() T) error {
-	cursor, err := m.coll.Find(context.TODO(), query)
+	return m.SeedAllContext(context.Background(), query, listParam, initItem)
+}
+
+// SeedAllContext is SeedAll with its span, query and ingests under ctx.
+func (m *PaginateMongoSeeder[T]) SeedAllContext(ctx context.Context, query bson.D, listParam []string, initItem func() T) error {
+	ctx, span := m.paginationClient.StartSpan(ctx, "PaginateMongoSeeder.SeedAll")
+	span.SetAttributes(pageflow.AttributeSeeded.Bool(true))
+
+	items, err := m.seedAll(ctx, query, listParam, initItem)
+
+	span.SetAttributes(pageflow.AttributeItemsReturned.Int64(items))
+	pageflow.EndSpan(span, err)
+	return err
+}
+
+func (m *PaginateMongoSeeder[T]) seedAll(ctx context.Context, query bson.D, listParam []string, initItem func() T) (int64, error) {
+	seedStart := time.Now()
+
+	cursor, err := m.coll.Find(ctx, query)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(ctx)
 
-	for cursor.Next(context.TODO()) {
+	report := &pageflow.SeedReport{}
+	ingest := func(ctx context.Context, item T) error {
+		return m.paginationClient.IngestItemContext(ctx, item, listParam, true)
+	}
+	for cursor.Next(ctx) {
 		item := initItem()
 		errorDecode := cursor.Decode(&item)
 		if errorDecode != nil {
+			report.Fail("", pageflow.StageDecode, errorDecode)
+			if m.strict {
+				return report.Seeded, report
+			}
 			continue
 		}
 
-		m.baseClient.Set(item)
-		m.paginationClient.IngestItem(item, listParam, true)
+		err = pageflow.SeedItem(ctx, report, m.baseClient, item, ingest)
+		if err != nil && m.strict {
+			return report.Seeded, report
+		}
 	}
 
-	return nil
+	err = cursor.Err()
+	if err != nil {
+		report.Fail("", pageflow.StageRead, err)
+	} else {
+		err = m.paginationClient.MarkFresh(listParam)
+		if err != nil {
+			report.Fail("", pageflow.StageMarker, err)
+		}
+	}
+
+	m.paginationClient.RecordSeed(report.Seeded, time.Since(seedStart))
+	return report.Seeded, report.Err()
 }
 
 func NewPaginateMongoSeederWithReference[T pageflow.MongoItemBlueprint](coll *mongo.Collection, baseClient *pageflow.Base[T], paginateClient *pageflow.Paginate[T], sortingReference string) *PaginateMongoSeeder[T] {
@@ -202,37 +312,84 @@ type SortedMongoSeeder[T pageflow.MongoItemBlueprint] struct {
 	baseClient   *pageflow.Base[T]
 	sortedClient *pageflow.Sorted[T]
 	scoringField string
+	strict       bool
+}
+
+func (s *SortedMongoSeeder[T]) SetStrict(strict bool) {
+	s.strict = strict
 }
 
 func (s *SortedMongoSeeder[T]) Seed(query bson.D, listParam []string, initItem func() T) error {
+	return s.SeedContext(context.Background(), query, listParam, initItem)
+}
+
+func (s *SortedMongoSeeder[T]) SeedContext(ctx context.Context, query bson.D, listParam []string, initItem func() T) error {
+	ctx, span := s.sortedClient.StartSpan(ctx, "SortedMongoSeeder.Seed")
+	span.SetAttributes(pageflow.AttributeSeeded.Bool(true))
+
+	items, err := s.seed(ctx, query, listParam, initItem)
+
+	span.SetAttributes(pageflow.AttributeItemsReturned.Int64(items))
+	pageflow.EndSpan(span, err)
+	return err
+}
+
+func (s *SortedMongoSeeder[T]) seed(ctx context.Context, query bson.D, listParam []string, initItem func() T) (int64, error) {
+	seedStart := time.Now()
+
 	if query == nil {
 		query = bson.D{}
 	}
 
-	cursor, err := s.coll.Find(context.TODO(), query)
+	cursor, err := s.coll.Find(ctx, query)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(ctx)
 
 	var counterLoop int64
-	for cursor.Next(context.TODO()) {
+	report := &pageflow.SeedReport{}
+	ingest := func(ctx context.Context, item T) error {
+		return s.sortedClient.IngestItemContext(ctx, item, listParam, true)
+	}
+	for cursor.Next(ctx) {
 		item := initItem()
 		errorDecode := cursor.Decode(&item)
 		if errorDecode != nil {
+			report.Fail("", pageflow.StageDecode, errorDecode)
+			if s.strict {
+				return report.Seeded, report
+			}
 			continue
 		}
-
-		s.baseClient.Set(item)
-		s.sortedClient.IngestItem(item, listParam, true)
 		counterLoop++
+
+		err = pageflow.SeedItem(ctx, report, s.baseClient, item, ingest)
+		if err != nil && s.strict {
+			return report.Seeded, report
+		}
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		report.Fail("", pageflow.StageRead, err)
+		return report.Seeded, report
 	}
 
 	if counterLoop == 0 {
-		s.sortedClient.SetBlankPage(listParam)
+		err = s.sortedClient.SetBlankPage(listParam)
+		if err != nil {
+			report.Fail("", pageflow.StageMarker, err)
+		}
+	}
+
+	err = s.sortedClient.MarkFresh(listParam)
+	if err != nil {
+		report.Fail("", pageflow.StageMarker, err)
 	}
 
-	return nil
+	s.sortedClient.RecordSeed(report.Seeded, time.Since(seedStart))
+	return report.Seeded, report.Err()
 }
 
 func getFieldValue(obj interface{}, fieldName string) interface{} {
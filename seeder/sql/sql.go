@@ -4,17 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/lefalya/pageflow"
 	"reflect"
 	"strconv"
 	"time"
 )
 
+// These wrap the pageflow sentinel errors, so errors.Is(err, pageflow.ErrNotFound)
+// and friends hold for them too.
 var (
-	NoDatabaseProvided           = errors.New("No database provided!")
-	DocumentOrReferencesNotFound = errors.New("Document or References not found!")
-	QueryOrScannerNotConfigured  = errors.New("Required queries or scanner not configured")
-	NilConfiguration             = errors.New("No configuration found!")
+	NoDatabaseProvided           = fmt.Errorf("%w: No database provided!", pageflow.ErrInvalidParam)
+	DocumentOrReferencesNotFound = fmt.Errorf("%w: Document or References not found!", pageflow.ErrNotFound)
+	QueryOrScannerNotConfigured  = fmt.Errorf("%w: Required queries or scanner not configured", pageflow.ErrInvalidParam)
+	NilConfiguration             = fmt.Errorf("%w: No configuration found!", pageflow.ErrInvalidParam)
 )
 
 type RowScanner[T pageflow.SQLItemBlueprint] func(row *sql.Row) (T, error)
@@ -26,9 +29,21 @@ type PaginateSQLSeeder[T pageflow.SQLItemBlueprint] struct {
 	baseClient       *pageflow.Base[T]
 	paginationClient *pageflow.Paginate[T]
 	scoringField     string
+	strict           bool
+}
+
+// SetStrict makes seeding stop at the first failed row instead of skipping
+// it. Either way failures are returned as a *pageflow.SeedReport.
+func (s *PaginateSQLSeeder[T]) SetStrict(strict bool) {
+	s.strict = strict
 }
 
 func (s *PaginateSQLSeeder[T]) FindOne(rowQuery string, rowScanner RowScanner[T], queryArgs []interface{}) (T, error) {
+	return s.FindOneContext(context.Background(), rowQuery, rowScanner, queryArgs)
+}
+
+// FindOneContext is FindOne with its query under ctx.
+func (s *PaginateSQLSeeder[T]) FindOneContext(ctx context.Context, rowQuery string, rowScanner RowScanner[T], queryArgs []interface{}) (T, error) {
 	var item T
 	if s.db == nil {
 		return item, NoDatabaseProvided
@@ -38,7 +53,7 @@ func (s *PaginateSQLSeeder[T]) FindOne(rowQuery string, rowScanner RowScanner[T]
 		return item, QueryOrScannerNotConfigured
 	}
 
-	row := s.db.QueryRowContext(context.TODO(), rowQuery, queryArgs...)
+	row := s.db.QueryRowContext(ctx, rowQuery, queryArgs...)
 
 	item, err := rowScanner(row)
 	if err != nil {
@@ -52,29 +67,60 @@ func (s *PaginateSQLSeeder[T]) FindOne(rowQuery string, rowScanner RowScanner[T]
 }
 
 func (s *PaginateSQLSeeder[T]) SeedOne(rowQuery string, rowScanner RowScanner[T], queryArgs []interface{}) error {
-	item, err := s.FindOne(rowQuery, rowScanner, queryArgs)
-	if err != nil {
-		return err
+	return s.SeedOneContext(context.Background(), rowQuery, rowScanner, queryArgs)
+}
+
+// SeedOneContext is SeedOne with its span and query under ctx.
+func (s *PaginateSQLSeeder[T]) SeedOneContext(ctx context.Context, rowQuery string, rowScanner RowScanner[T], queryArgs []interface{}) error {
+	ctx, span := s.paginationClient.StartSpan(ctx, "PaginateSQLSeeder.SeedOne")
+	span.SetAttributes(pageflow.AttributeSeeded.Bool(true))
+
+	item, err := s.FindOneContext(ctx, rowQuery, rowScanner, queryArgs)
+	if err == nil {
+		err = s.baseClient.Set(item)
 	}
 
-	return s.baseClient.Set(item)
+	pageflow.EndSpan(span, err)
+	return err
 }
 
 func (s *PaginateSQLSeeder[T]) SeedPartial(rowQuery string, firstPageQuery string, nextPageQuery string, rowScanner RowScanner[T], rowsScanner RowsScanner[T], queryArgs []interface{}, subtraction int64, lastRandId string, paginateParams []string) error {
+	return s.SeedPartialContext(context.Background(), rowQuery, firstPageQuery, nextPageQuery, rowScanner, rowsScanner, queryArgs, subtraction, lastRandId, paginateParams)
+}
+
+// SeedPartialContext is SeedPartial with its span, queries and ingests under
+// ctx.
+func (s *PaginateSQLSeeder[T]) SeedPartialContext(ctx context.Context, rowQuery string, firstPageQuery string, nextPageQuery string, rowScanner RowScanner[T], rowsScanner RowsScanner[T], queryArgs []interface{}, subtraction int64, lastRandId string, paginateParams []string) error {
+	ctx, span := s.paginationClient.StartSpan(ctx, "PaginateSQLSeeder.SeedPartial")
+	span.SetAttributes(pageflow.AttributeSeeded.Bool(true))
+
+	items, err := s.seedPartial(ctx, rowQuery, firstPageQuery, nextPageQuery, rowScanner, rowsScanner, queryArgs, subtraction, lastRandId, paginateParams)
+
+	span.SetAttributes(pageflow.AttributeItemsReturned.Int64(items))
+	pageflow.EndSpan(span, err)
+	return err
+}
+
+func (s *PaginateSQLSeeder[T]) seedPartial(ctx context.Context, rowQuery string, firstPageQuery string, nextPageQuery string, rowScanner RowScanner[T], rowsScanner RowsScanner[T], queryArgs []interface{}, subtraction int64, lastRandId string, paginateParams []string) (int64, error) {
+	seedStart := time.Now()
+
 	var firstPage bool
 	var queryToUse string
 
 	if s.db == nil {
-		return NoDatabaseProvided
+		return 0, NoDatabaseProvided
 	}
 
 	if lastRandId == "" {
 		firstPage = true
 		queryToUse = firstPageQuery
 	} else {
-		reference, err := s.FindOne(rowQuery, rowScanner, []interface{}{lastRandId})
+		reference, err := s.FindOneContext(ctx, rowQuery, rowScanner, []interface{}{lastRandId})
 		if err != nil {
-			return DocumentOrReferencesNotFound
+			if errors.Is(err, DocumentOrReferencesNotFound) {
+				return 0, fmt.Errorf("%w: %w", pageflow.ErrCursorInvalid, err)
+			}
+			return 0, err
 		} else {
 			firstPage = false
 			queryToUse = nextPageQuery
@@ -94,33 +140,61 @@ func (s *PaginateSQLSeeder[T]) SeedPartial(rowQuery string, firstPageQuery strin
 	}
 	queryToUse = queryToUse + ` LIMIT ` + strconv.FormatInt(limit, 10)
 
-	rows, err := s.db.QueryContext(context.TODO(), queryToUse, queryArgs...)
+	rows, err := s.db.QueryContext(ctx, queryToUse, queryArgs...)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer rows.Close()
 
 	var counterLoop int64 = 0
+	report := &pageflow.SeedReport{}
+	ingest := func(ctx context.Context, item T) error {
+		return s.paginationClient.IngestItemContext(ctx, item, paginateParams, true)
+	}
 	for rows.Next() {
 		item, err := rowsScanner(rows)
 		if err != nil {
+			report.Fail("", pageflow.StageDecode, err)
+			if s.strict {
+				return report.Seeded, report
+			}
 			continue
 		}
-
-		s.baseClient.Set(item)
-		s.paginationClient.IngestItem(item, paginateParams, true)
 		counterLoop++
+
+		err = pageflow.SeedItem(ctx, report, s.baseClient, item, ingest)
+		if err != nil && s.strict {
+			return report.Seeded, report
+		}
+
+		// with bound fields a page without params takes those of its first item
+		if err == nil && paginateParams == nil {
+			paginateParams, _ = s.paginationClient.ParamsOf(item)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		report.Fail("", pageflow.StageRead, err)
+		return report.Seeded, report
 	}
 
 	if firstPage && counterLoop == 0 {
-		s.paginationClient.SetBlankPage(paginateParams)
+		err = s.paginationClient.SetBlankPage(paginateParams)
 	} else if firstPage && counterLoop > 0 && counterLoop < s.paginationClient.GetItemPerPage() {
-		s.paginationClient.SetFirstPage(paginateParams)
+		err = s.paginationClient.SetFirstPage(paginateParams)
 	} else if !firstPage && subtraction+counterLoop < s.paginationClient.GetItemPerPage() {
-		s.paginationClient.SetLastPage(paginateParams)
+		err = s.paginationClient.SetLastPage(paginateParams)
+	}
+	if err == nil && firstPage {
+		err = s.paginationClient.MarkFresh(paginateParams)
+	}
+	if err != nil {
+		report.Fail("", pageflow.StageMarker, err)
 	}
 
-	return nil
+	s.paginationClient.RecordSeed(report.Seeded, time.Since(seedStart))
+	return report.Seeded, report.Err()
 }
 
 func NewPaginateSQLSeeder[T pageflow.SQLItemBlueprint](db *sql.DB, baseClient *pageflow.Base[T], paginateClient *pageflow.Paginate[T]) *PaginateSQLSeeder[T] {
@@ -136,6 +210,11 @@ type SortedSQLSeeder[T pageflow.SQLItemBlueprint] struct {
 	baseClient   *pageflow.Base[T]
 	sortedClient *pageflow.Sorted[T]
 	scoringField string
+	strict       bool
+}
+
+func (s *SortedSQLSeeder[T]) SetStrict(strict bool) {
+	s.strict = strict
 }
 
 func (s *SortedSQLSeeder[T]) SeedAll(
@@ -144,37 +223,91 @@ func (s *SortedSQLSeeder[T]) SeedAll(
 	args []interface{},
 	keyParam []string,
 ) error {
+	return s.SeedAllContext(context.Background(), query, rowsScanner, args, keyParam)
+}
+
+func (s *SortedSQLSeeder[T]) SeedAllContext(
+	ctx context.Context,
+	query string,
+	rowsScanner RowsScanner[T],
+	args []interface{},
+	keyParam []string,
+) error {
+	ctx, span := s.sortedClient.StartSpan(ctx, "SortedSQLSeeder.SeedAll")
+	span.SetAttributes(pageflow.AttributeSeeded.Bool(true))
+
+	items, err := s.seedAll(ctx, query, rowsScanner, args, keyParam)
+
+	span.SetAttributes(pageflow.AttributeItemsReturned.Int64(items))
+	pageflow.EndSpan(span, err)
+	return err
+}
+
+func (s *SortedSQLSeeder[T]) seedAll(
+	ctx context.Context,
+	query string,
+	rowsScanner RowsScanner[T],
+	args []interface{},
+	keyParam []string,
+) (int64, error) {
+	seedStart := time.Now()
+
 	if s.db == nil {
-		return NoDatabaseProvided
+		return 0, NoDatabaseProvided
 	}
 
 	if rowsScanner == nil {
-		return QueryOrScannerNotConfigured
+		return 0, QueryOrScannerNotConfigured
 	}
 
-	rows, err := s.db.QueryContext(context.TODO(), query, args...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer rows.Close()
 
 	var counterLoop int64
+	report := &pageflow.SeedReport{}
+	ingest := func(ctx context.Context, item T) error {
+		return s.sortedClient.IngestItemContext(ctx, item, keyParam, true)
+	}
 	for rows.Next() {
 		item, err := rowsScanner(rows)
 		if err != nil {
+			report.Fail("", pageflow.StageDecode, err)
+			if s.strict {
+				return report.Seeded, report
+			}
 			continue
 		}
-
-		s.baseClient.Set(item)
-		s.sortedClient.IngestItem(item, keyParam, true)
 		counterLoop++
+
+		err = pageflow.SeedItem(ctx, report, s.baseClient, item, ingest)
+		if err != nil && s.strict {
+			return report.Seeded, report
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		report.Fail("", pageflow.StageRead, err)
+		return report.Seeded, report
 	}
 
 	if counterLoop == 0 {
-		s.sortedClient.SetBlankPage(keyParam)
+		err = s.sortedClient.SetBlankPage(keyParam)
+		if err != nil {
+			report.Fail("", pageflow.StageMarker, err)
+		}
+	}
+
+	err = s.sortedClient.MarkFresh(keyParam)
+	if err != nil {
+		report.Fail("", pageflow.StageMarker, err)
 	}
 
-	return nil
+	s.sortedClient.RecordSeed(report.Seeded, time.Since(seedStart))
+	return report.Seeded, report.Err()
 }
 
 func NewSortedSQLSeeder[T pageflow.SQLItemBlueprint](
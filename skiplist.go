@@ -0,0 +1,222 @@
+package pageflow
+
+import (
+	"math/rand"
+)
+
+const (
+	skiplistMaxLevel    = 32
+	skiplistProbability = 0.25
+)
+
+// skiplist orders members by score, then by member, the same way Redis orders
+// a sorted set. Every forward link records how many nodes it skips so ranks
+// are found in O(log n).
+type skiplist struct {
+	head   *skiplistNode
+	tail   *skiplistNode
+	length int64
+	level  int
+	random *rand.Rand
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	levels   []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int64
+}
+
+func newSkiplist(seed int64) *skiplist {
+	return &skiplist{
+		head:   &skiplistNode{levels: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+		random: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (sl *skiplist) randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && sl.random.Float64() < skiplistProbability {
+		level++
+	}
+	return level
+}
+
+// precedes reports whether a node sorts strictly before (score, member).
+func (node *skiplistNode) precedes(score float64, member string) bool {
+	return node.score < score || (node.score == score && node.member < member)
+}
+
+func (sl *skiplist) insert(member string, score float64) {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int64
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		if i < sl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.levels[i].forward != nil && x.levels[i].forward.precedes(score, member) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	level := sl.randomLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = sl.head
+			update[i].levels[i].span = sl.length
+		}
+		sl.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, levels: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < sl.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != sl.head {
+		x.backward = update[0]
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		sl.tail = x
+	}
+	sl.length++
+}
+
+func (sl *skiplist) delete(member string, score float64) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.precedes(score, member) {
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.levels[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+	sl.unlink(x, update[:])
+	return true
+}
+
+func (sl *skiplist) unlink(x *skiplistNode, update []*skiplistNode) {
+	for i := 0; i < sl.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		sl.tail = x.backward
+	}
+
+	for sl.level > 1 && sl.head.levels[sl.level-1].forward == nil {
+		sl.level--
+	}
+	sl.length--
+}
+
+// rank returns the zero-based position of (score, member), or -1.
+func (sl *skiplist) rank(member string, score float64) int64 {
+	var rank int64
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && (x.levels[i].forward.precedes(score, member) ||
+			(x.levels[i].forward.score == score && x.levels[i].forward.member == member)) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if x != sl.head && x.score == score && x.member == member {
+			return rank - 1
+		}
+	}
+	return -1
+}
+
+// byRank returns the node at the zero-based rank, or nil.
+func (sl *skiplist) byRank(rank int64) *skiplistNode {
+	if rank < 0 || rank >= sl.length {
+		return nil
+	}
+
+	var traversed int64
+	target := rank + 1
+
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= target {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if traversed == target {
+			return x
+		}
+	}
+	return nil
+}
+
+// firstInRange returns the lowest node whose score satisfies min, or nil.
+func (sl *skiplist) firstInRange(min ScoreBound) *skiplistNode {
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !aboveMin(x.levels[i].forward.score, min) {
+			x = x.levels[i].forward
+		}
+	}
+	return x.levels[0].forward
+}
+
+// lastInRange returns the highest node whose score satisfies max, or nil.
+func (sl *skiplist) lastInRange(max ScoreBound) *skiplistNode {
+	x := sl.head
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && belowMax(x.levels[i].forward.score, max) {
+			x = x.levels[i].forward
+		}
+	}
+	if x == sl.head {
+		return nil
+	}
+	return x
+}
+
+func aboveMin(score float64, min ScoreBound) bool {
+	if min.Exclusive {
+		return score > min.Score
+	}
+	return score >= min.Score
+}
+
+func belowMax(score float64, max ScoreBound) bool {
+	if max.Exclusive {
+		return score < max.Score
+	}
+	return score <= max.Score
+}
@@ -0,0 +1,129 @@
+package pageflow
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ZMember is one entry of a sorted set.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// ZRangeQuery selects members of a sorted set by score. Rev walks from Max
+// down to Min. A Count of zero or less returns every member after Offset.
+type ZRangeQuery struct {
+	Key    string
+	Min    ScoreBound
+	Max    ScoreBound
+	Rev    bool
+	Offset int64
+	Count  int64
+}
+
+// ZWrite adds Member to the sorted set Key as part of a CheckAndSet or
+// HCheckAndReplace, refreshing its TTL. With XX it only re-scores a member
+// that is already there, like ZADD XX.
+type ZWrite struct {
+	Key    string
+	Member ZMember
+	TTL    time.Duration
+	XX     bool
+}
+
+// Store is the set of key-value, hash, sorted set and expiry operations
+// pageflow needs from its backend. NewRedisStore adapts a Redis client and
+// NewMemoryStore keeps everything in process.
+//
+// Missing keys and members are reported with redis.Nil. Writes that replace a
+// whole key (Set, SetNX, CheckAndSet, HReplace and HCheckAndReplace) given a
+// ttl of zero leave it without expiry, like a plain SET; every other write
+// given a ttl of zero leaves the expiry of the key untouched. Methods ending
+// in Many run one operation per key and let the backend batch them; their
+// results are aligned with the keys given.
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	// MGet returns, for each key, its string value or nil when it is missing.
+	MGet(ctx context.Context, keys ...string) ([]interface{}, error)
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	// SetNX writes key only when it does not exist and reports whether it did.
+	SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error)
+	// CheckAndSet calls check with the current value of key and, when it
+	// returns nil, writes value and applies zwrites in one transaction. It
+	// fails with ErrConflict when key is written by someone else between the
+	// read and the write. On a Redis Cluster, zwrites to another slot than key
+	// are applied right after the transaction instead.
+	CheckAndSet(ctx context.Context, key string, check func(current string, exists bool) error, value string, ttl time.Duration, zwrites ...ZWrite) error
+	// BackendKey returns key as the backend stores it, e.g. with the prefix
+	// of a namespaced Store. Redis Cluster slots are computed from it.
+	BackendKey(key string) string
+	Del(ctx context.Context, keys ...string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	ExpireMany(ctx context.Context, keys []string, ttl time.Duration) error
+	// TTLMany returns, for each key, its remaining time to live: zero when
+	// the key is missing and a negative duration when it does not expire.
+	TTLMany(ctx context.Context, keys []string) ([]time.Duration, error)
+	// ScanPrefix calls batch with every key starting with prefix, a few keys at
+	// a time, without blocking the backend. Keys written during the scan may
+	// or may not be seen.
+	ScanPrefix(ctx context.Context, prefix string, batch func(keys []string) error) error
+
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HGetAllMany(ctx context.Context, keys []string) ([]map[string]string, error)
+	// HMGet returns, for each field, its value or nil when it is missing.
+	HMGet(ctx context.Context, key string, fields ...string) ([]interface{}, error)
+	HMGetMany(ctx context.Context, keys []string, fields []string) ([][]interface{}, error)
+	// HReplace atomically replaces the whole hash with values.
+	HReplace(ctx context.Context, key string, values map[string]string, ttl time.Duration) error
+	// HCheckAndReplace is CheckAndSet for HReplace. A missing hash is passed
+	// to check as an empty map.
+	HCheckAndReplace(ctx context.Context, key string, check func(current map[string]string) error, values map[string]string, ttl time.Duration, zwrites ...ZWrite) error
+	// HSetIfExists writes values only when the hash already exists.
+	HSetIfExists(ctx context.Context, key string, values map[string]string, ttl time.Duration) error
+	// HIncrByIfExists increments field only when the hash already exists.
+	HIncrByIfExists(ctx context.Context, key string, field string, delta int64, ttl time.Duration) (int64, error)
+
+	ZAdd(ctx context.Context, key string, ttl time.Duration, members ...ZMember) error
+	// ZAddMany adds the same member to every key.
+	ZAddMany(ctx context.Context, keys []string, member ZMember, ttl time.Duration) []error
+	ZRem(ctx context.Context, key string, members ...string) error
+	ZCard(ctx context.Context, key string) (int64, error)
+	ZCardMany(ctx context.Context, keys []string) ([]int64, error)
+	// ZRange selects by rank; negative ranks count from the end.
+	ZRange(ctx context.Context, key string, start int64, stop int64, rev bool) ([]ZMember, error)
+	ZRangeMany(ctx context.Context, keys []string, start int64, stop int64, rev bool) ([][]ZMember, error)
+	ZRangeByScore(ctx context.Context, query ZRangeQuery) ([]ZMember, error)
+	ZRangeByScoreMany(ctx context.Context, queries []ZRangeQuery) ([][]ZMember, error)
+	ZRank(ctx context.Context, key string, member string, rev bool) (int64, error)
+	ZScore(ctx context.Context, key string, member string) (float64, error)
+	ZCount(ctx context.Context, key string, min ScoreBound, max ScoreBound) (int64, error)
+	ZRemRangeByRank(ctx context.Context, key string, start int64, stop int64) (int64, error)
+	ZRemRangeByRankMany(ctx context.Context, keys []string, start int64, stop int64) []error
+	ZIncrBy(ctx context.Context, key string, member string, delta float64, ttl time.Duration) (float64, error)
+	// ZIncrWithTieBreak adds delta to the integer part of the member's score
+	// and replaces its fractional part with fraction. It returns the new
+	// integer part.
+	ZIncrWithTieBreak(ctx context.Context, key string, member string, delta float64, fraction float64, ttl time.Duration) (float64, error)
+	// ZStore writes the union or intersection of keys into destination,
+	// keeping the highest score of each member.
+	ZStore(ctx context.Context, destination string, keys []string, union bool, ttl time.Duration) (int64, error)
+	// ZUnion returns the union of keys, keeping the highest score of each
+	// member, in ascending order.
+	ZUnion(ctx context.Context, keys []string) ([]ZMember, error)
+
+	Publish(ctx context.Context, channel string, message string) error
+	// Subscribe calls handler for every message published on channel until the
+	// returned Closer is closed.
+	Subscribe(ctx context.Context, channel string, handler func(message string)) (io.Closer, error)
+}
+
+func zMembers(members []ZMember) []string {
+	names := make([]string, len(members))
+	for i, member := range members {
+		names[i] = member.Member
+	}
+	return names
+}
@@ -0,0 +1,1028 @@
+package pageflow
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// MemoryStore is a thread-safe, in-process Store. Sorted sets are backed by a
+// skiplist like their Redis counterpart and expiry is evaluated lazily against
+// an injectable clock, which makes TTL behaviour testable without sleeping.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	clock   func() time.Time
+	seed    int64
+	strings map[string]string
+	hashes  map[string]map[string]string
+	zsets   map[string]*memorySortedSet
+	expires map[string]time.Time
+
+	subscribers    map[string]map[int64]func(message string)
+	nextSubscriber int64
+
+	slotCheck bool
+}
+
+type memorySortedSet struct {
+	scores map[string]float64
+	list   *skiplist
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		clock:   time.Now,
+		strings: make(map[string]string),
+		hashes:  make(map[string]map[string]string),
+		zsets:   make(map[string]*memorySortedSet),
+		expires: make(map[string]time.Time),
+
+		subscribers: make(map[string]map[int64]func(message string)),
+	}
+}
+
+// SetClock replaces the time source used to evaluate expiry.
+func (ms *MemoryStore) SetClock(clock func() time.Time) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.clock = clock
+}
+
+// SetSlotCheck makes multi-key commands fail with CROSSSLOT when their keys
+// hash to different slots, as they would on Redis Cluster, so tests can check
+// key colocation without a cluster. Commands the Redis store already splits
+// per slot, such as MGet and Del, are not checked.
+func (ms *MemoryStore) SetSlotCheck(enabled bool) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.slotCheck = enabled
+}
+
+func (ms *MemoryStore) checkSlots(keys ...string) error {
+	if ms.slotCheck && len(keys) > 1 && !sameSlot(keys...) {
+		return errCrossSlot
+	}
+	return nil
+}
+
+// TTL returns the remaining time to live of key, or zero when the key is
+// missing or does not expire.
+func (ms *MemoryStore) TTL(key string) time.Duration {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if !ms.exists(key) {
+		return 0
+	}
+	expireAt, ok := ms.expires[key]
+	if !ok {
+		return 0
+	}
+	return expireAt.Sub(ms.clock())
+}
+
+// Keys returns every live key.
+func (ms *MemoryStore) Keys() []string {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	var keys []string
+	for key := range ms.strings {
+		keys = append(keys, key)
+	}
+	for key := range ms.hashes {
+		keys = append(keys, key)
+	}
+	for key := range ms.zsets {
+		keys = append(keys, key)
+	}
+
+	live := keys[:0]
+	for _, key := range keys {
+		if ms.exists(key) {
+			live = append(live, key)
+		}
+	}
+	sort.Strings(live)
+	return live
+}
+
+func (ms *MemoryStore) ScanPrefix(ctx context.Context, prefix string, batch func(keys []string) error) error {
+	var matched []string
+	for _, key := range ms.Keys() {
+		if strings.HasPrefix(key, prefix) {
+			matched = append(matched, key)
+		}
+	}
+
+	for start := 0; start < len(matched); start += SCAN_COUNT {
+		end := start + SCAN_COUNT
+		if end > len(matched) {
+			end = len(matched)
+		}
+
+		err := batch(matched[start:end])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evict drops key if its expiry has passed.
+func (ms *MemoryStore) evict(key string) {
+	expireAt, ok := ms.expires[key]
+	if ok && !ms.clock().Before(expireAt) {
+		ms.remove(key)
+	}
+}
+
+func (ms *MemoryStore) remove(key string) {
+	delete(ms.strings, key)
+	delete(ms.hashes, key)
+	delete(ms.zsets, key)
+	delete(ms.expires, key)
+}
+
+func (ms *MemoryStore) exists(key string) bool {
+	ms.evict(key)
+	if _, ok := ms.strings[key]; ok {
+		return true
+	}
+	if _, ok := ms.hashes[key]; ok {
+		return true
+	}
+	_, ok := ms.zsets[key]
+	return ok
+}
+
+func (ms *MemoryStore) expire(key string, ttl time.Duration) {
+	if ttl > 0 && ms.exists(key) {
+		ms.expires[key] = ms.clock().Add(ttl)
+	}
+}
+
+func (ms *MemoryStore) checkType(key string, kind string) error {
+	ms.evict(key)
+	_, isString := ms.strings[key]
+	_, isHash := ms.hashes[key]
+	_, isZset := ms.zsets[key]
+
+	if (isString && kind != "string") || (isHash && kind != "hash") || (isZset && kind != "zset") {
+		return errWrongType
+	}
+	return nil
+}
+
+func (ms *MemoryStore) zset(key string, create bool) (*memorySortedSet, error) {
+	err := ms.checkType(key, "zset")
+	if err != nil {
+		return nil, err
+	}
+
+	zset, ok := ms.zsets[key]
+	if !ok && create {
+		ms.seed++
+		zset = &memorySortedSet{scores: make(map[string]float64), list: newSkiplist(ms.seed)}
+		ms.zsets[key] = zset
+	}
+	return zset, nil
+}
+
+func (ms *MemoryStore) BackendKey(key string) string {
+	return key
+}
+
+func (ms *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	err := ms.checkType(key, "string")
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := ms.strings[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func (ms *MemoryStore) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		ms.evict(key)
+		if value, ok := ms.strings[key]; ok {
+			values[i] = value
+		}
+	}
+	return values, nil
+}
+
+func (ms *MemoryStore) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.remove(key)
+	ms.strings[key] = value
+	ms.expire(key, ttl)
+	return nil
+}
+
+func (ms *MemoryStore) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if ms.exists(key) {
+		return false, nil
+	}
+	ms.strings[key] = value
+	ms.expire(key, ttl)
+	return true, nil
+}
+
+func (ms *MemoryStore) CheckAndSet(ctx context.Context, key string, check func(current string, exists bool) error, value string, ttl time.Duration, zwrites ...ZWrite) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	err := ms.checkType(key, "string")
+	if err != nil {
+		return err
+	}
+
+	current, exists := ms.strings[key]
+	err = check(current, exists)
+	if err != nil {
+		return err
+	}
+
+	ms.strings[key] = value
+	delete(ms.expires, key)
+	ms.expire(key, ttl)
+	return ms.zWrite(zwrites)
+}
+
+func (ms *MemoryStore) zWrite(zwrites []ZWrite) error {
+	for _, zwrite := range zwrites {
+		if zwrite.XX {
+			zset, err := ms.zset(zwrite.Key, false)
+			if err != nil {
+				return err
+			}
+			if zset == nil {
+				continue
+			}
+			if _, ok := zset.scores[zwrite.Member.Member]; !ok {
+				continue
+			}
+		}
+
+		err := ms.zAdd(zwrite.Key, zwrite.TTL, zwrite.Member)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ms *MemoryStore) Del(ctx context.Context, keys ...string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, key := range keys {
+		ms.remove(key)
+	}
+	return nil
+}
+
+func (ms *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.exists(key), nil
+}
+
+func (ms *MemoryStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.expire(key, ttl)
+	return nil
+}
+
+func (ms *MemoryStore) ExpireMany(ctx context.Context, keys []string, ttl time.Duration) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, key := range keys {
+		ms.expire(key, ttl)
+	}
+	return nil
+}
+
+func (ms *MemoryStore) TTLMany(ctx context.Context, keys []string) ([]time.Duration, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ttls := make([]time.Duration, len(keys))
+	for i, key := range keys {
+		if !ms.exists(key) {
+			continue
+		}
+		expireAt, ok := ms.expires[key]
+		if !ok {
+			ttls[i] = -1
+			continue
+		}
+		ttls[i] = expireAt.Sub(ms.clock())
+	}
+	return ttls, nil
+}
+
+func (ms *MemoryStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.hGetAll(key)
+}
+
+func (ms *MemoryStore) hGetAll(key string) (map[string]string, error) {
+	err := ms.checkType(key, "hash")
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(ms.hashes[key]))
+	for field, value := range ms.hashes[key] {
+		values[field] = value
+	}
+	return values, nil
+}
+
+func (ms *MemoryStore) HGetAllMany(ctx context.Context, keys []string) ([]map[string]string, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	values := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		hash, err := ms.hGetAll(key)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = hash
+	}
+	return values, nil
+}
+
+func (ms *MemoryStore) HMGet(ctx context.Context, key string, fields ...string) ([]interface{}, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.hmGet(key, fields)
+}
+
+func (ms *MemoryStore) hmGet(key string, fields []string) ([]interface{}, error) {
+	err := ms.checkType(key, "hash")
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		if value, ok := ms.hashes[key][field]; ok {
+			values[i] = value
+		}
+	}
+	return values, nil
+}
+
+func (ms *MemoryStore) HMGetMany(ctx context.Context, keys []string, fields []string) ([][]interface{}, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	values := make([][]interface{}, len(keys))
+	for i, key := range keys {
+		hash, err := ms.hmGet(key, fields)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = hash
+	}
+	return values, nil
+}
+
+func (ms *MemoryStore) HReplace(ctx context.Context, key string, values map[string]string, ttl time.Duration) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.remove(key)
+	if len(values) == 0 {
+		return nil
+	}
+
+	hash := make(map[string]string, len(values))
+	for field, value := range values {
+		hash[field] = value
+	}
+	ms.hashes[key] = hash
+	ms.expire(key, ttl)
+	return nil
+}
+
+func (ms *MemoryStore) HCheckAndReplace(ctx context.Context, key string, check func(current map[string]string) error, values map[string]string, ttl time.Duration, zwrites ...ZWrite) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	err := ms.checkType(key, "hash")
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]string, len(ms.hashes[key]))
+	for field, value := range ms.hashes[key] {
+		current[field] = value
+	}
+	err = check(current)
+	if err != nil {
+		return err
+	}
+
+	ms.remove(key)
+	if len(values) > 0 {
+		hash := make(map[string]string, len(values))
+		for field, value := range values {
+			hash[field] = value
+		}
+		ms.hashes[key] = hash
+		ms.expire(key, ttl)
+	}
+	return ms.zWrite(zwrites)
+}
+
+func (ms *MemoryStore) HSetIfExists(ctx context.Context, key string, values map[string]string, ttl time.Duration) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	err := ms.checkType(key, "hash")
+	if err != nil {
+		return err
+	}
+
+	hash, ok := ms.hashes[key]
+	if !ok {
+		return redis.Nil
+	}
+	for field, value := range values {
+		hash[field] = value
+	}
+	ms.expire(key, ttl)
+	return nil
+}
+
+func (ms *MemoryStore) HIncrByIfExists(ctx context.Context, key string, field string, delta int64, ttl time.Duration) (int64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	err := ms.checkType(key, "hash")
+	if err != nil {
+		return 0, err
+	}
+
+	hash, ok := ms.hashes[key]
+	if !ok {
+		return 0, redis.Nil
+	}
+
+	var current int64
+	if raw, ok := hash[field]; ok {
+		current, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return 0, errors.New("ERR hash value is not an integer")
+		}
+	}
+
+	current += delta
+	hash[field] = strconv.FormatInt(current, 10)
+	ms.expire(key, ttl)
+	return current, nil
+}
+
+func (zset *memorySortedSet) add(member string, score float64) {
+	if current, ok := zset.scores[member]; ok {
+		if current == score {
+			return
+		}
+		zset.list.delete(member, current)
+	}
+	zset.scores[member] = score
+	zset.list.insert(member, score)
+}
+
+func (zset *memorySortedSet) remove(member string) {
+	if score, ok := zset.scores[member]; ok {
+		zset.list.delete(member, score)
+		delete(zset.scores, member)
+	}
+}
+
+func (ms *MemoryStore) zAdd(key string, ttl time.Duration, members ...ZMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	zset, err := ms.zset(key, true)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		zset.add(member.Member, member.Score)
+	}
+	ms.expire(key, ttl)
+	return nil
+}
+
+func (ms *MemoryStore) ZAdd(ctx context.Context, key string, ttl time.Duration, members ...ZMember) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.zAdd(key, ttl, members...)
+}
+
+func (ms *MemoryStore) ZAddMany(ctx context.Context, keys []string, member ZMember, ttl time.Duration) []error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		errs[i] = ms.zAdd(key, ttl, member)
+	}
+	return errs
+}
+
+func (ms *MemoryStore) ZRem(ctx context.Context, key string, members ...string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	zset, err := ms.zset(key, false)
+	if err != nil || zset == nil {
+		return err
+	}
+
+	for _, member := range members {
+		zset.remove(member)
+	}
+	ms.dropIfEmpty(key, zset)
+	return nil
+}
+
+func (ms *MemoryStore) dropIfEmpty(key string, zset *memorySortedSet) {
+	if zset.list.length == 0 {
+		ms.remove(key)
+	}
+}
+
+func (ms *MemoryStore) zCard(key string) (int64, error) {
+	zset, err := ms.zset(key, false)
+	if err != nil || zset == nil {
+		return 0, err
+	}
+	return zset.list.length, nil
+}
+
+func (ms *MemoryStore) ZCard(ctx context.Context, key string) (int64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.zCard(key)
+}
+
+func (ms *MemoryStore) ZCardMany(ctx context.Context, keys []string) ([]int64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	cards := make([]int64, len(keys))
+	for i, key := range keys {
+		card, err := ms.zCard(key)
+		if err != nil {
+			return nil, err
+		}
+		cards[i] = card
+	}
+	return cards, nil
+}
+
+// normalizeRange resolves negative ranks and clamps them the way Redis does.
+func normalizeRange(start int64, stop int64, length int64) (int64, int64, bool) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	return start, stop, start <= stop && start < length
+}
+
+func (ms *MemoryStore) zRange(key string, start int64, stop int64, rev bool) ([]ZMember, error) {
+	zset, err := ms.zset(key, false)
+	if err != nil || zset == nil {
+		return nil, err
+	}
+
+	length := zset.list.length
+	start, stop, ok := normalizeRange(start, stop, length)
+	if !ok {
+		return []ZMember{}, nil
+	}
+
+	members := make([]ZMember, 0, stop-start+1)
+	if rev {
+		x := zset.list.byRank(length - 1 - start)
+		for i := start; i <= stop && x != nil; i++ {
+			members = append(members, ZMember{Member: x.member, Score: x.score})
+			x = x.backward
+		}
+	} else {
+		x := zset.list.byRank(start)
+		for i := start; i <= stop && x != nil; i++ {
+			members = append(members, ZMember{Member: x.member, Score: x.score})
+			x = x.levels[0].forward
+		}
+	}
+	return members, nil
+}
+
+func (ms *MemoryStore) ZRange(ctx context.Context, key string, start int64, stop int64, rev bool) ([]ZMember, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.zRange(key, start, stop, rev)
+}
+
+func (ms *MemoryStore) ZRangeMany(ctx context.Context, keys []string, start int64, stop int64, rev bool) ([][]ZMember, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	members := make([][]ZMember, len(keys))
+	for i, key := range keys {
+		batch, err := ms.zRange(key, start, stop, rev)
+		if err != nil {
+			return nil, err
+		}
+		members[i] = batch
+	}
+	return members, nil
+}
+
+func (ms *MemoryStore) zRangeByScore(query ZRangeQuery) ([]ZMember, error) {
+	zset, err := ms.zset(query.Key, false)
+	if err != nil || zset == nil {
+		return nil, err
+	}
+
+	var members []ZMember
+	var skipped int64
+	if query.Rev {
+		for x := zset.list.lastInRange(query.Max); x != nil && aboveMin(x.score, query.Min); x = x.backward {
+			if skipped < query.Offset {
+				skipped++
+				continue
+			}
+			if query.Count > 0 && int64(len(members)) == query.Count {
+				break
+			}
+			members = append(members, ZMember{Member: x.member, Score: x.score})
+		}
+	} else {
+		for x := zset.list.firstInRange(query.Min); x != nil && belowMax(x.score, query.Max); x = x.levels[0].forward {
+			if skipped < query.Offset {
+				skipped++
+				continue
+			}
+			if query.Count > 0 && int64(len(members)) == query.Count {
+				break
+			}
+			members = append(members, ZMember{Member: x.member, Score: x.score})
+		}
+	}
+	return members, nil
+}
+
+func (ms *MemoryStore) ZRangeByScore(ctx context.Context, query ZRangeQuery) ([]ZMember, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.zRangeByScore(query)
+}
+
+func (ms *MemoryStore) ZRangeByScoreMany(ctx context.Context, queries []ZRangeQuery) ([][]ZMember, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	members := make([][]ZMember, len(queries))
+	for i, query := range queries {
+		batch, err := ms.zRangeByScore(query)
+		if err != nil {
+			return nil, err
+		}
+		members[i] = batch
+	}
+	return members, nil
+}
+
+func (ms *MemoryStore) ZRank(ctx context.Context, key string, member string, rev bool) (int64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	zset, err := ms.zset(key, false)
+	if err != nil {
+		return 0, err
+	}
+	if zset == nil {
+		return 0, redis.Nil
+	}
+
+	score, ok := zset.scores[member]
+	if !ok {
+		return 0, redis.Nil
+	}
+
+	rank := zset.list.rank(member, score)
+	if rev {
+		return zset.list.length - 1 - rank, nil
+	}
+	return rank, nil
+}
+
+func (ms *MemoryStore) ZScore(ctx context.Context, key string, member string) (float64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	zset, err := ms.zset(key, false)
+	if err != nil {
+		return 0, err
+	}
+	if zset == nil {
+		return 0, redis.Nil
+	}
+
+	score, ok := zset.scores[member]
+	if !ok {
+		return 0, redis.Nil
+	}
+	return score, nil
+}
+
+func (ms *MemoryStore) ZCount(ctx context.Context, key string, min ScoreBound, max ScoreBound) (int64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	zset, err := ms.zset(key, false)
+	if err != nil || zset == nil {
+		return 0, err
+	}
+
+	first := zset.list.firstInRange(min)
+	last := zset.list.lastInRange(max)
+	if first == nil || last == nil {
+		return 0, nil
+	}
+
+	count := zset.list.rank(last.member, last.score) - zset.list.rank(first.member, first.score) + 1
+	if count < 0 {
+		return 0, nil
+	}
+	return count, nil
+}
+
+func (ms *MemoryStore) zRemRangeByRank(key string, start int64, stop int64) (int64, error) {
+	zset, err := ms.zset(key, false)
+	if err != nil || zset == nil {
+		return 0, err
+	}
+
+	start, stop, ok := normalizeRange(start, stop, zset.list.length)
+	if !ok {
+		return 0, nil
+	}
+
+	var removed int64
+	x := zset.list.byRank(start)
+	for i := start; i <= stop && x != nil; i++ {
+		next := x.levels[0].forward
+		zset.remove(x.member)
+		removed++
+		x = next
+	}
+	ms.dropIfEmpty(key, zset)
+	return removed, nil
+}
+
+func (ms *MemoryStore) ZRemRangeByRank(ctx context.Context, key string, start int64, stop int64) (int64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.zRemRangeByRank(key, start, stop)
+}
+
+func (ms *MemoryStore) ZRemRangeByRankMany(ctx context.Context, keys []string, start int64, stop int64) []error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		_, errs[i] = ms.zRemRangeByRank(key, start, stop)
+	}
+	return errs
+}
+
+func (ms *MemoryStore) ZIncrBy(ctx context.Context, key string, member string, delta float64, ttl time.Duration) (float64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	zset, err := ms.zset(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	score := zset.scores[member] + delta
+	zset.add(member, score)
+	ms.expire(key, ttl)
+	return score, nil
+}
+
+func (ms *MemoryStore) ZIncrWithTieBreak(ctx context.Context, key string, member string, delta float64, fraction float64, ttl time.Duration) (float64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	zset, err := ms.zset(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	points := math.Floor(zset.scores[member]) + delta
+	zset.add(member, points+fraction)
+	ms.expire(key, ttl)
+	return points, nil
+}
+
+func (ms *MemoryStore) combine(keys []string, union bool) (map[string]float64, error) {
+	var combined map[string]float64
+
+	for i, key := range keys {
+		zset, err := ms.zset(key, false)
+		if err != nil {
+			return nil, err
+		}
+
+		scores := map[string]float64{}
+		if zset != nil {
+			scores = zset.scores
+		}
+
+		if i == 0 {
+			combined = make(map[string]float64, len(scores))
+			for member, score := range scores {
+				combined[member] = score
+			}
+			continue
+		}
+
+		if union {
+			for member, score := range scores {
+				if current, ok := combined[member]; !ok || score > current {
+					combined[member] = score
+				}
+			}
+		} else {
+			for member, current := range combined {
+				score, ok := scores[member]
+				if !ok {
+					delete(combined, member)
+				} else if score > current {
+					combined[member] = score
+				}
+			}
+		}
+	}
+
+	return combined, nil
+}
+
+func (ms *MemoryStore) ZStore(ctx context.Context, destination string, keys []string, union bool, ttl time.Duration) (int64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	err := ms.checkSlots(append([]string{destination}, keys...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	combined, err := ms.combine(keys, union)
+	if err != nil {
+		return 0, err
+	}
+
+	ms.remove(destination)
+	if len(combined) == 0 {
+		return 0, nil
+	}
+
+	members := make([]ZMember, 0, len(combined))
+	for member, score := range combined {
+		members = append(members, ZMember{Member: member, Score: score})
+	}
+
+	err = ms.zAdd(destination, ttl, members...)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(members)), nil
+}
+
+func (ms *MemoryStore) ZUnion(ctx context.Context, keys []string) ([]ZMember, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	err := ms.checkSlots(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	combined, err := ms.combine(keys, true)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]ZMember, 0, len(combined))
+	for member, score := range combined {
+		members = append(members, ZMember{Member: member, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].Member < members[j].Member
+	})
+	return members, nil
+}
+
+// Publish delivers message to every subscriber of channel before returning.
+func (ms *MemoryStore) Publish(ctx context.Context, channel string, message string) error {
+	ms.mutex.Lock()
+	handlers := make([]func(message string), 0, len(ms.subscribers[channel]))
+	for _, handler := range ms.subscribers[channel] {
+		handlers = append(handlers, handler)
+	}
+	ms.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+func (ms *MemoryStore) Subscribe(ctx context.Context, channel string, handler func(message string)) (io.Closer, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.nextSubscriber++
+	if ms.subscribers[channel] == nil {
+		ms.subscribers[channel] = make(map[int64]func(message string))
+	}
+	ms.subscribers[channel][ms.nextSubscriber] = handler
+
+	return &memorySubscription{store: ms, channel: channel, id: ms.nextSubscriber}, nil
+}
+
+type memorySubscription struct {
+	store   *MemoryStore
+	channel string
+	id      int64
+}
+
+func (sub *memorySubscription) Close() error {
+	sub.store.mutex.Lock()
+	defer sub.store.mutex.Unlock()
+
+	delete(sub.store.subscribers[sub.channel], sub.id)
+	return nil
+}
@@ -0,0 +1,702 @@
+package pageflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	hashSetIfExistsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+redis.call('HSET', KEYS[1], unpack(ARGV, 2))
+if tonumber(ARGV[1]) > 0 then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+	hashIncrByIfExistsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+local value = redis.call('HINCRBY', KEYS[1], ARGV[2], ARGV[3])
+if tonumber(ARGV[1]) > 0 then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return value
+`)
+
+	// incrementWithTieScript keeps the integer part of the score as the points
+	// and rewrites the fraction on every increment.
+	incrementWithTieScript = redis.NewScript(`
+local current = redis.call('ZSCORE', KEYS[1], ARGV[1])
+local points = 0
+if current then
+	points = math.floor(tonumber(current))
+end
+points = points + tonumber(ARGV[2])
+redis.call('ZADD', KEYS[1], points + tonumber(ARGV[3]), ARGV[1])
+if tonumber(ARGV[4]) > 0 then
+	redis.call('EXPIRE', KEYS[1], ARGV[4])
+end
+return tostring(points)
+`)
+)
+
+// globEscaper escapes the characters SCAN MATCH treats as patterns.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+type redisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore adapts a Redis client, standalone or cluster, to Store.
+func NewRedisStore(client redis.UniversalClient) Store {
+	return &redisStore{client: client}
+}
+
+func (rs *redisStore) BackendKey(key string) string {
+	return key
+}
+
+func (rs *redisStore) Get(ctx context.Context, key string) (string, error) {
+	return rs.client.Get(ctx, key).Result()
+}
+
+// MGet sends one MGET per hash slot on a cluster, pipelined so each node is
+// visited once, and a single MGET otherwise.
+func (rs *redisStore) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	if _, ok := rs.client.(*redis.ClusterClient); !ok {
+		return rs.client.MGet(ctx, keys...).Result()
+	}
+
+	groups := groupBySlot(keys)
+	if len(groups) == 1 {
+		return rs.client.MGet(ctx, keys...).Result()
+	}
+
+	pipe := rs.client.Pipeline()
+	results := make([]*redis.SliceCmd, len(groups))
+	for i, group := range groups {
+		slotKeys := make([]string, len(group))
+		for j, index := range group {
+			slotKeys[j] = keys[index]
+		}
+		results[i] = pipe.MGet(ctx, slotKeys...)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, group := range groups {
+		for j, value := range results[i].Val() {
+			values[group[j]] = value
+		}
+	}
+	return values, nil
+}
+
+func (rs *redisStore) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return rs.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (rs *redisStore) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	return rs.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+// CheckAndSet reads key under WATCH, so the SET in MULTI is discarded when
+// another client writes key in between.
+func (rs *redisStore) CheckAndSet(ctx context.Context, key string, check func(current string, exists bool) error, value string, ttl time.Duration, zwrites ...ZWrite) error {
+	read := func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, key).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		return check(current, err == nil)
+	}
+	write := func(pipe redis.Pipeliner) {
+		pipe.Set(ctx, key, value, ttl)
+	}
+	return rs.checkAndWrite(ctx, key, read, write, zwrites)
+}
+
+// checkAndWrite runs read under WATCH key and queues write in MULTI. Outside
+// a cluster all zwrites join the MULTI; on one only those in the slot of key
+// can, and the rest follow once it committed.
+func (rs *redisStore) checkAndWrite(ctx context.Context, key string, read func(tx *redis.Tx) error, write func(pipe redis.Pipeliner), zwrites []ZWrite) error {
+	inline := zwrites
+	var after []ZWrite
+	if _, ok := rs.client.(*redis.ClusterClient); ok {
+		inline = nil
+		for _, zwrite := range zwrites {
+			if sameSlot(key, zwrite.Key) {
+				inline = append(inline, zwrite)
+			} else {
+				after = append(after, zwrite)
+			}
+		}
+	}
+
+	err := rs.client.Watch(ctx, func(tx *redis.Tx) error {
+		err := read(tx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			write(pipe)
+			queueZWrites(ctx, pipe, inline)
+			return nil
+		})
+		return err
+	}, key)
+	if err != nil {
+		return watchConflict(err, key)
+	}
+	if len(after) == 0 {
+		return nil
+	}
+
+	pipe := rs.client.Pipeline()
+	queueZWrites(ctx, pipe, after)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func queueZWrites(ctx context.Context, pipe redis.Pipeliner, zwrites []ZWrite) {
+	for _, zwrite := range zwrites {
+		member := redis.Z{Score: zwrite.Member.Score, Member: zwrite.Member.Member}
+		if zwrite.XX {
+			pipe.ZAddXX(ctx, zwrite.Key, member)
+		} else {
+			pipe.ZAdd(ctx, zwrite.Key, member)
+		}
+		if zwrite.TTL > 0 {
+			pipe.Expire(ctx, zwrite.Key, zwrite.TTL)
+		}
+	}
+}
+
+func watchConflict(err error, key string) error {
+	if errors.Is(err, redis.TxFailedErr) {
+		return fmt.Errorf("%w: %s", ErrConflict, key)
+	}
+	return err
+}
+
+// Del removes each key with its own DEL so keys may live in different cluster
+// slots.
+func (rs *redisStore) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if len(keys) == 1 {
+		return rs.client.Del(ctx, keys[0]).Err()
+	}
+
+	pipe := rs.client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (rs *redisStore) Exists(ctx context.Context, key string) (bool, error) {
+	exists, err := rs.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists > 0, nil
+}
+
+func (rs *redisStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return rs.client.Expire(ctx, key, ttl).Err()
+}
+
+func (rs *redisStore) ExpireMany(ctx context.Context, keys []string, ttl time.Duration) error {
+	if len(keys) == 0 || ttl <= 0 {
+		return nil
+	}
+
+	pipe := rs.client.Pipeline()
+	for _, key := range keys {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// TTLMany maps the -2 Redis replies for missing keys to zero and keeps the -1
+// replies for keys without expiry.
+func (rs *redisStore) TTLMany(ctx context.Context, keys []string) ([]time.Duration, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := rs.client.Pipeline()
+	results := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		results[i] = pipe.TTL(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ttls := make([]time.Duration, len(keys))
+	for i, result := range results {
+		switch ttl := result.Val(); {
+		case ttl == -2:
+			ttls[i] = 0
+		case ttl < 0:
+			ttls[i] = -1
+		default:
+			ttls[i] = ttl
+		}
+	}
+	return ttls, nil
+}
+
+// ScanPrefix runs SCAN on every master of a cluster, one node at a time, and
+// on the only node otherwise.
+func (rs *redisStore) ScanPrefix(ctx context.Context, prefix string, batch func(keys []string) error) error {
+	match := globEscaper.Replace(prefix) + "*"
+
+	cluster, ok := rs.client.(*redis.ClusterClient)
+	if !ok {
+		return scanNode(ctx, rs.client, match, batch)
+	}
+
+	var mutex sync.Mutex
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return scanNode(ctx, node, match, func(keys []string) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return batch(keys)
+		})
+	})
+}
+
+func scanNode(ctx context.Context, client redis.Cmdable, match string, batch func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, match, SCAN_COUNT).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			err = batch(keys)
+			if err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (rs *redisStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return rs.client.HGetAll(ctx, key).Result()
+}
+
+func (rs *redisStore) HGetAllMany(ctx context.Context, keys []string) ([]map[string]string, error) {
+	pipe := rs.client.Pipeline()
+	results := make([]*redis.MapStringStringCmd, len(keys))
+	for i, key := range keys {
+		results[i] = pipe.HGetAll(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make([]map[string]string, len(keys))
+	for i, result := range results {
+		values[i] = result.Val()
+	}
+	return values, nil
+}
+
+func (rs *redisStore) HMGet(ctx context.Context, key string, fields ...string) ([]interface{}, error) {
+	return rs.client.HMGet(ctx, key, fields...).Result()
+}
+
+func (rs *redisStore) HMGetMany(ctx context.Context, keys []string, fields []string) ([][]interface{}, error) {
+	pipe := rs.client.Pipeline()
+	results := make([]*redis.SliceCmd, len(keys))
+	for i, key := range keys {
+		results[i] = pipe.HMGet(ctx, key, fields...)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make([][]interface{}, len(keys))
+	for i, result := range results {
+		values[i] = result.Val()
+	}
+	return values, nil
+}
+
+func (rs *redisStore) HReplace(ctx context.Context, key string, values map[string]string, ttl time.Duration) error {
+	pipe := rs.client.TxPipeline()
+	pipe.Del(ctx, key)
+	if len(values) > 0 {
+		pipe.HSet(ctx, key, values)
+	}
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (rs *redisStore) HCheckAndReplace(ctx context.Context, key string, check func(current map[string]string) error, values map[string]string, ttl time.Duration, zwrites ...ZWrite) error {
+	read := func(tx *redis.Tx) error {
+		current, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		return check(current)
+	}
+	write := func(pipe redis.Pipeliner) {
+		pipe.Del(ctx, key)
+		if len(values) > 0 {
+			pipe.HSet(ctx, key, values)
+		}
+		if ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+		}
+	}
+	return rs.checkAndWrite(ctx, key, read, write, zwrites)
+}
+
+func (rs *redisStore) HSetIfExists(ctx context.Context, key string, values map[string]string, ttl time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	args := []interface{}{int64(ttl.Seconds())}
+	for field, value := range values {
+		args = append(args, field, value)
+	}
+
+	return hashSetIfExistsScript.Run(ctx, rs.client, []string{key}, args...).Err()
+}
+
+func (rs *redisStore) HIncrByIfExists(ctx context.Context, key string, field string, delta int64, ttl time.Duration) (int64, error) {
+	return hashIncrByIfExistsScript.Run(ctx, rs.client, []string{key}, int64(ttl.Seconds()), field, delta).Int64()
+}
+
+func (rs *redisStore) ZAdd(ctx context.Context, key string, ttl time.Duration, members ...ZMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	pipe := rs.client.TxPipeline()
+	pipe.ZAdd(ctx, key, toRedisZ(members)...)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (rs *redisStore) ZAddMany(ctx context.Context, keys []string, member ZMember, ttl time.Duration) []error {
+	pipe := rs.client.Pipeline()
+	adds := make([]*redis.IntCmd, len(keys))
+	expires := make([]*redis.BoolCmd, len(keys))
+	for i, key := range keys {
+		adds[i] = pipe.ZAdd(ctx, key, redis.Z{Score: member.Score, Member: member.Member})
+		if ttl > 0 {
+			expires[i] = pipe.Expire(ctx, key, ttl)
+		}
+	}
+	pipe.Exec(ctx)
+
+	errs := make([]error, len(keys))
+	for i := range keys {
+		errs[i] = adds[i].Err()
+		if errs[i] == nil && expires[i] != nil {
+			errs[i] = expires[i].Err()
+		}
+	}
+	return errs
+}
+
+func (rs *redisStore) ZRem(ctx context.Context, key string, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	return rs.client.ZRem(ctx, key, values...).Err()
+}
+
+func (rs *redisStore) ZCard(ctx context.Context, key string) (int64, error) {
+	return rs.client.ZCard(ctx, key).Result()
+}
+
+func (rs *redisStore) ZCardMany(ctx context.Context, keys []string) ([]int64, error) {
+	pipe := rs.client.Pipeline()
+	results := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		results[i] = pipe.ZCard(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]int64, len(keys))
+	for i, result := range results {
+		cards[i] = result.Val()
+	}
+	return cards, nil
+}
+
+func (rs *redisStore) ZRange(ctx context.Context, key string, start int64, stop int64, rev bool) ([]ZMember, error) {
+	var result *redis.ZSliceCmd
+	if rev {
+		result = rs.client.ZRevRangeWithScores(ctx, key, start, stop)
+	} else {
+		result = rs.client.ZRangeWithScores(ctx, key, start, stop)
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	return fromRedisZ(result.Val()), nil
+}
+
+func (rs *redisStore) ZRangeMany(ctx context.Context, keys []string, start int64, stop int64, rev bool) ([][]ZMember, error) {
+	pipe := rs.client.Pipeline()
+	results := make([]*redis.ZSliceCmd, len(keys))
+	for i, key := range keys {
+		if rev {
+			results[i] = pipe.ZRevRangeWithScores(ctx, key, start, stop)
+		} else {
+			results[i] = pipe.ZRangeWithScores(ctx, key, start, stop)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([][]ZMember, len(keys))
+	for i, result := range results {
+		members[i] = fromRedisZ(result.Val())
+	}
+	return members, nil
+}
+
+func (rs *redisStore) ZRangeByScore(ctx context.Context, query ZRangeQuery) ([]ZMember, error) {
+	result := rs.client.ZRangeArgsWithScores(ctx, toRedisRangeArgs(query))
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	return fromRedisZ(result.Val()), nil
+}
+
+func (rs *redisStore) ZRangeByScoreMany(ctx context.Context, queries []ZRangeQuery) ([][]ZMember, error) {
+	pipe := rs.client.Pipeline()
+	results := make([]*redis.ZSliceCmd, len(queries))
+	for i, query := range queries {
+		results[i] = pipe.ZRangeArgsWithScores(ctx, toRedisRangeArgs(query))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	members := make([][]ZMember, len(queries))
+	for i, result := range results {
+		members[i] = fromRedisZ(result.Val())
+	}
+	return members, nil
+}
+
+func (rs *redisStore) ZRank(ctx context.Context, key string, member string, rev bool) (int64, error) {
+	if rev {
+		return rs.client.ZRevRank(ctx, key, member).Result()
+	}
+	return rs.client.ZRank(ctx, key, member).Result()
+}
+
+func (rs *redisStore) ZScore(ctx context.Context, key string, member string) (float64, error) {
+	return rs.client.ZScore(ctx, key, member).Result()
+}
+
+func (rs *redisStore) ZCount(ctx context.Context, key string, min ScoreBound, max ScoreBound) (int64, error) {
+	return rs.client.ZCount(ctx, key, min.String(), max.String()).Result()
+}
+
+func (rs *redisStore) ZRemRangeByRank(ctx context.Context, key string, start int64, stop int64) (int64, error) {
+	return rs.client.ZRemRangeByRank(ctx, key, start, stop).Result()
+}
+
+func (rs *redisStore) ZRemRangeByRankMany(ctx context.Context, keys []string, start int64, stop int64) []error {
+	pipe := rs.client.Pipeline()
+	results := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		results[i] = pipe.ZRemRangeByRank(ctx, key, start, stop)
+	}
+	pipe.Exec(ctx)
+
+	errs := make([]error, len(keys))
+	for i, result := range results {
+		errs[i] = result.Err()
+	}
+	return errs
+}
+
+func (rs *redisStore) ZIncrBy(ctx context.Context, key string, member string, delta float64, ttl time.Duration) (float64, error) {
+	pipe := rs.client.TxPipeline()
+	increment := pipe.ZIncrBy(ctx, key, delta, member)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return increment.Val(), nil
+}
+
+func (rs *redisStore) ZIncrWithTieBreak(ctx context.Context, key string, member string, delta float64, fraction float64, ttl time.Duration) (float64, error) {
+	result := incrementWithTieScript.Run(ctx, rs.client, []string{key}, member, delta, fraction, int64(ttl.Seconds()))
+	if result.Err() != nil {
+		return 0, result.Err()
+	}
+
+	points, err := result.Text()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(points, 64)
+}
+
+func (rs *redisStore) ZStore(ctx context.Context, destination string, keys []string, union bool, ttl time.Duration) (int64, error) {
+	store := &redis.ZStore{
+		Keys:      keys,
+		Aggregate: "MAX",
+	}
+
+	pipe := rs.client.TxPipeline()
+	var count *redis.IntCmd
+	if union {
+		count = pipe.ZUnionStore(ctx, destination, store)
+	} else {
+		count = pipe.ZInterStore(ctx, destination, store)
+	}
+	if ttl > 0 {
+		pipe.Expire(ctx, destination, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+func (rs *redisStore) ZUnion(ctx context.Context, keys []string) ([]ZMember, error) {
+	result := rs.client.ZUnionWithScores(ctx, redis.ZStore{
+		Keys:      keys,
+		Aggregate: "MAX",
+	})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	return fromRedisZ(result.Val()), nil
+}
+
+func toRedisRangeArgs(query ZRangeQuery) redis.ZRangeArgs {
+	count := query.Count
+	if count <= 0 {
+		count = -1
+	}
+
+	// go-redis swaps Start and Stop itself when Rev is set
+	return redis.ZRangeArgs{
+		Key:     query.Key,
+		Start:   query.Min.String(),
+		Stop:    query.Max.String(),
+		ByScore: true,
+		Rev:     query.Rev,
+		Offset:  query.Offset,
+		Count:   count,
+	}
+}
+
+func toRedisZ(members []ZMember) []redis.Z {
+	z := make([]redis.Z, len(members))
+	for i, member := range members {
+		z[i] = redis.Z{Score: member.Score, Member: member.Member}
+	}
+	return z
+}
+
+func fromRedisZ(z []redis.Z) []ZMember {
+	members := make([]ZMember, len(z))
+	for i, member := range z {
+		value, _ := member.Member.(string)
+		members[i] = ZMember{Member: value, Score: member.Score}
+	}
+	return members
+}
+
+func (rs *redisStore) Publish(ctx context.Context, channel string, message string) error {
+	return rs.client.Publish(ctx, channel, message).Err()
+}
+
+func (rs *redisStore) Subscribe(ctx context.Context, channel string, handler func(message string)) (io.Closer, error) {
+	pubsub := rs.client.Subscribe(ctx, channel)
+
+	// wait for the subscription to be confirmed so no message published after
+	// Subscribe returns is missed
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	messages := pubsub.Channel()
+	go func() {
+		for message := range messages {
+			handler(message.Payload)
+		}
+	}()
+
+	return pubsub, nil
+}
@@ -0,0 +1,222 @@
+package pageflow
+
+import (
+	"context"
+	"errors"
+	"github.com/lefalya/item"
+	"time"
+)
+
+// TOMBSTONE_TTL is how long a deleted item is kept from being seeded again by
+// default. Tombstones are short-lived markers: a seeder that read an item from
+// the database before it was deleted would otherwise write it straight back.
+// Base.Del marks the item itself and RemoveItem marks it for one sorted set
+// only; SeedItem and IngestItem in seed mode skip marked items.
+//
+// Seeding writes first and checks for a tombstone right after, undoing the
+// write when it finds one, while deletions write their tombstone before they
+// delete. Whichever way the two interleave, a deleted item does not outlive
+// both.
+const TOMBSTONE_TTL = time.Minute * 5
+
+// errTombstoned stops IngestItem from seeding a deleted item. The public
+// methods report it as success, since skipping the item is what the caller
+// wants.
+var errTombstoned = errors.New("item was deleted")
+
+// SetTombstoneTTL changes how long Del keeps a deleted item from being seeded
+// again. Zero disables tombstones.
+func (cr *Base[T]) SetTombstoneTTL(ttl time.Duration) {
+	cr.tombstoneTTL = ttl
+}
+
+func (cr *Base[T]) tombstoneKey(randId string) (string, error) {
+	key, err := cr.itemKey(randId)
+	if err != nil {
+		return "", err
+	}
+	return key + ":tombstone", nil
+}
+
+func (cr *Base[T]) tombstone(ctx context.Context, randId string) error {
+	if cr.tombstoneTTL <= 0 {
+		return nil
+	}
+
+	key, err := cr.tombstoneKey(randId)
+	if err != nil {
+		return err
+	}
+	return cr.store.Set(ctx, key, "1", cr.tombstoneTTL)
+}
+
+// IsTombstoned reports whether the item was deleted with Del within the
+// tombstone TTL.
+func (cr *Base[T]) IsTombstoned(randId string) (bool, error) {
+	return cr.isTombstoned(context.TODO(), randId)
+}
+
+func (cr *Base[T]) isTombstoned(ctx context.Context, randId string) (bool, error) {
+	if cr.tombstoneTTL <= 0 {
+		return false, nil
+	}
+
+	key, err := cr.tombstoneKey(randId)
+	if err != nil {
+		return false, err
+	}
+	return cr.store.Exists(ctx, key)
+}
+
+// SetTombstoneTTL changes how long RemoveItem keeps an item from being seeded
+// into the same sorted set again. Zero disables tombstones.
+func (cr *SortedSet[T]) SetTombstoneTTL(ttl time.Duration) {
+	cr.tombstoneTTL = ttl
+}
+
+func (cr *Paginate[T]) SetTombstoneTTL(ttl time.Duration) {
+	cr.sortedSetClient.SetTombstoneTTL(ttl)
+}
+
+func (srtd *Sorted[T]) SetTombstoneTTL(ttl time.Duration) {
+	srtd.sortedSetClient.SetTombstoneTTL(ttl)
+}
+
+func (cr *SortedSet[T]) tombstoneKey(param []string, randId string) (string, error) {
+	key, err := cr.key(param)
+	if err != nil {
+		return "", err
+	}
+	return setTombstoneKey(key, randId), nil
+}
+
+func setTombstoneKey(sortedSetKey string, randId string) string {
+	return sortedSetKey + ":tombstone:" + randId
+}
+
+func (cr *SortedSet[T]) tombstone(ctx context.Context, param []string, randId string) error {
+	if cr.tombstoneTTL <= 0 {
+		return nil
+	}
+
+	key, err := cr.tombstoneKey(param, randId)
+	if err != nil {
+		return err
+	}
+	return cr.store.Set(ctx, key, "1", cr.tombstoneTTL)
+}
+
+// clearTombstone lifts the tombstone of an item that is explicitly added back,
+// outside of seeding.
+func (cr *SortedSet[T]) clearTombstone(ctx context.Context, param []string, randId string) error {
+	if cr.tombstoneTTL <= 0 {
+		return nil
+	}
+
+	key, err := cr.tombstoneKey(param, randId)
+	if err != nil {
+		return err
+	}
+	return cr.store.Del(ctx, key)
+}
+
+// tombstones reports whether randId was deleted from base, and whether it was
+// removed from the sorted set of param, within the tombstone TTL. Both markers
+// are read with one MGET.
+func tombstones[T item.Blueprint](ctx context.Context, base *Base[T], sortedSet *SortedSet[T], param []string, randId string) (bool, bool, error) {
+	var baseKey, setKey string
+	var keys []string
+	if base.tombstoneTTL > 0 {
+		key, err := base.tombstoneKey(randId)
+		if err != nil {
+			return false, false, err
+		}
+		baseKey = key
+		keys = append(keys, key)
+	}
+	if sortedSet.tombstoneTTL > 0 {
+		key, err := sortedSet.tombstoneKey(param, randId)
+		if err != nil {
+			return false, false, err
+		}
+		setKey = key
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return false, false, nil
+	}
+
+	values, err := sortedSet.store.MGet(ctx, keys...)
+	if err != nil {
+		return false, false, err
+	}
+
+	var inBase, inSet bool
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		switch keys[i] {
+		case baseKey:
+			inBase = true
+		case setKey:
+			inSet = true
+		}
+	}
+	return inBase, inSet, nil
+}
+
+// tombstoned reports whether randId was deleted from base, or removed from the
+// sorted set of param, within the tombstone TTL.
+func tombstoned[T item.Blueprint](ctx context.Context, base *Base[T], sortedSet *SortedSet[T], param []string, randId string) (bool, error) {
+	inBase, inSet, err := tombstones(ctx, base, sortedSet, param, randId)
+	return inBase || inSet, err
+}
+
+// seedState is what the ingest of one SeedItem learned about the tombstones
+// of its item. SeedItem passes it down in the context.
+type seedState struct {
+	// checked is set once the Base tombstone was read after the item was
+	// written, deleted when it was there.
+	checked bool
+	deleted bool
+	// skipped is set when the item was taken back out of a sorted set.
+	skipped bool
+}
+
+type seedKey struct{}
+
+func seedStateOf(ctx context.Context) *seedState {
+	state, _ := ctx.Value(seedKey{}).(*seedState)
+	return state
+}
+
+// unseedTombstoned runs right after a seeded item was added to the sorted set
+// of param and takes it back out when it was deleted or removed within the
+// tombstone TTL, returning errTombstoned. Del and RemoveItem write their
+// tombstone before deleting, so a deletion racing the seed is either seen here
+// or comes after the add and undoes it itself.
+func unseedTombstoned[T item.Blueprint](ctx context.Context, base *Base[T], sortedSet *SortedSet[T], param []string, item T) error {
+	inBase, inSet, err := tombstones(ctx, base, sortedSet, param, item.GetRandId())
+	if err != nil {
+		return err
+	}
+
+	state := seedStateOf(ctx)
+	if state != nil && base.tombstoneTTL > 0 {
+		state.checked = true
+		state.deleted = inBase
+	}
+	if !inBase && !inSet {
+		return nil
+	}
+
+	err = sortedSet.deleteFromSortedSet(ctx, param, item)
+	if err != nil {
+		return err
+	}
+	if state != nil {
+		state.skipped = true
+	}
+	return errTombstoned
+}
@@ -0,0 +1,83 @@
+package pageflow
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/lefalya/pageflow"
+
+// Span attributes set by pageflow. Seeders outside this package use them too,
+// so every span of one operation reads the same way.
+const (
+	AttributeKeyFormat       = attribute.Key("pageflow.key_format")
+	AttributeDirection       = attribute.Key("pageflow.direction")
+	AttributePageSize        = attribute.Key("pageflow.page_size")
+	AttributeItemsReturned   = attribute.Key("pageflow.items_returned")
+	AttributeDanglingSkipped = attribute.Key("pageflow.dangling_skipped")
+	AttributeSeeded          = attribute.Key("pageflow.seeded")
+	AttributeTombstoned      = attribute.Key("pageflow.tombstoned")
+)
+
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// danglingCounter counts the dangling ids of one operation while still
+// forwarding them to the configured Metrics.
+type danglingCounter struct {
+	Metrics
+	count int
+}
+
+func (dc *danglingCounter) DanglingItem(keyFormat string) {
+	dc.count++
+	dc.Metrics.DanglingItem(keyFormat)
+}
+
+func startSpan(ctx context.Context, tracer trace.Tracer, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if tracer == nil {
+		tracer = noopTracer
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// EndSpan records err on span, if any, and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// SetTracerProvider enables spans around Fetch, FetchAll, IngestItem and
+// RemoveItem. Without it no spans are recorded.
+func (cr *Paginate[T]) SetTracerProvider(provider trace.TracerProvider) {
+	cr.tracer = provider.Tracer(tracerName)
+}
+
+// StartSpan starts a span named operation carrying the key format, direction
+// and page size of this Paginate.
+func (cr *Paginate[T]) StartSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return startSpan(ctx, cr.tracer, operation,
+		AttributeKeyFormat.String(cr.sortedSetClient.sortedSetKeyFormat),
+		AttributeDirection.String(cr.direction),
+		AttributePageSize.Int64(cr.itemPerPage),
+	)
+}
+
+func (srtd *Sorted[T]) SetTracerProvider(provider trace.TracerProvider) {
+	srtd.tracer = provider.Tracer(tracerName)
+}
+
+func (srtd *Sorted[T]) StartSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return startSpan(ctx, srtd.tracer, operation,
+		AttributeKeyFormat.String(srtd.sortedSetClient.sortedSetKeyFormat),
+		AttributeDirection.String(srtd.direction),
+	)
+}
@@ -0,0 +1,215 @@
+package pageflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CONFLICT_RETRIES is how many times a versioned write is retried when another
+// writer touched the item between the version check and the write.
+const CONFLICT_RETRIES = 3
+
+// errOutdated makes a versioned write drop an item that is not newer than the
+// stored one.
+var errOutdated = errors.New("item is outdated")
+
+// SetVersion makes SetIfNewer and IngestIfNewer order items by version
+// instead of by UpdatedAt, e.g. by the sequence number of the change event an
+// item was built from. version must grow with every change of an item.
+func (cr *Base[T]) SetVersion(version func(item T) int64) {
+	cr.version = version
+}
+
+func (cr *Base[T]) versionOf(item T) int64 {
+	if cr.version != nil {
+		return cr.version(item)
+	}
+	return item.GetUpdatedAt().UnixNano()
+}
+
+// SetIfNewer is Set for change events that may arrive out of order: item is
+// written only when it is newer than the stored copy, and an item deleted
+// within the tombstone TTL stays deleted. It reports whether item was
+// written; an outdated item is dropped without an error.
+func (cr *Base[T]) SetIfNewer(item T) (bool, error) {
+	deleted, err := cr.IsTombstoned(item.GetRandId())
+	if err != nil || deleted {
+		return false, err
+	}
+	return cr.setIfNewer(context.TODO(), item)
+}
+
+// setIfNewer writes item and applies zwrites in one transaction unless the
+// stored item has the same or a newer version.
+func (cr *Base[T]) setIfNewer(ctx context.Context, item T, zwrites ...ZWrite) (bool, error) {
+	key, errKey := cr.itemKey(item.GetRandId())
+	if errKey != nil {
+		return false, errKey
+	}
+
+	version := cr.versionOf(item)
+	newer := func(stored T, exists bool) error {
+		if exists && version <= cr.versionOf(stored) {
+			return fmt.Errorf("%w: %s", errOutdated, key)
+		}
+		return nil
+	}
+
+	var err error
+	for attempt := 0; attempt <= CONFLICT_RETRIES; attempt++ {
+		err = cr.compareAndSet(ctx, key, item, newer, zwrites...)
+		if !errors.Is(err, ErrConflict) {
+			break
+		}
+	}
+	if errors.Is(err, errOutdated) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, cr.invalidate(EventItemUpdated, item.GetRandId(), key)
+}
+
+// IngestIfNewer is AddItem for change events that may arrive out of order. It
+// stores item in the Base and adds or re-scores it in the sorted set of param
+// in one transaction, and only when item is newer than the stored copy by
+// UpdatedAt or by the version given to Base.SetVersion. Items deleted within
+// the tombstone TTL are dropped. It reports whether item was applied.
+//
+// On a Redis Cluster the item key and the sorted set usually live in
+// different slots; the sorted set is then written right after the item, still
+// only when the item was newer.
+func (cr *Paginate[T]) IngestIfNewer(item T, param []string) (bool, error) {
+	return cr.IngestIfNewerContext(context.Background(), item, param)
+}
+
+// IngestIfNewerContext is IngestIfNewer with its span and store calls under
+// ctx.
+func (cr *Paginate[T]) IngestIfNewerContext(ctx context.Context, item T, param []string) (bool, error) {
+	ctx, span := cr.StartSpan(ctx, "Paginate.IngestIfNewer")
+	applied, err := cr.ingestIfNewer(ctx, item, param)
+	EndSpan(span, err)
+	return applied, err
+}
+
+func (cr *Paginate[T]) ingestIfNewer(ctx context.Context, item T, param []string) (bool, error) {
+	if cr.direction == "" {
+		return false, ErrDirectionUnset
+	}
+
+	param, err := bindParams(cr.binding, item, param)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := tombstoned(ctx, cr.baseClient, cr.sortedSetClient, param, item.GetRandId())
+	if err != nil || deleted {
+		return false, err
+	}
+
+	score, err := getItemScore(item, cr.sortingReference)
+	if err != nil {
+		return false, err
+	}
+
+	// the markers only change once the item turned out to be newer
+	admit, unmarks, err := cr.admission(ctx, param, float64(item.GetCreatedAt().UnixMilli()))
+	if err != nil {
+		return false, err
+	}
+
+	zwrite, err := cr.sortedSetClient.zWrite(param, score, item, !admit)
+	if err != nil {
+		return false, err
+	}
+
+	applied, err := cr.baseClient.setIfNewer(ctx, item, zwrite)
+	if err != nil || !applied {
+		return applied, err
+	}
+
+	err = cr.sortedSetClient.unmarkAll(ctx, param, unmarks)
+	if err != nil || !admit {
+		return true, err
+	}
+
+	err = cr.sortedSetClient.track(ctx, param, item.GetRandId())
+	if err != nil {
+		return true, err
+	}
+	return true, cr.trimSortedSet(ctx, param)
+}
+
+func (srtd *Sorted[T]) IngestIfNewer(item T, param []string) (bool, error) {
+	return srtd.IngestIfNewerContext(context.Background(), item, param)
+}
+
+func (srtd *Sorted[T]) IngestIfNewerContext(ctx context.Context, item T, param []string) (bool, error) {
+	ctx, span := srtd.StartSpan(ctx, "Sorted.IngestIfNewer")
+	applied, err := srtd.ingestIfNewer(ctx, item, param)
+	EndSpan(span, err)
+	return applied, err
+}
+
+func (srtd *Sorted[T]) ingestIfNewer(ctx context.Context, item T, param []string) (bool, error) {
+	param, err := bindParams(srtd.binding, item, param)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := tombstoned(ctx, srtd.baseClient, srtd.sortedSetClient, param, item.GetRandId())
+	if err != nil || deleted {
+		return false, err
+	}
+
+	score, err := getItemScore(item, srtd.sortingReference)
+	if err != nil {
+		return false, err
+	}
+
+	// the markers only change once the item turned out to be newer
+	admit, unmarks, err := srtd.admission(ctx, param)
+	if err != nil {
+		return false, err
+	}
+
+	zwrite, err := srtd.sortedSetClient.zWrite(param, score, item, !admit)
+	if err != nil {
+		return false, err
+	}
+
+	applied, err := srtd.baseClient.setIfNewer(ctx, item, zwrite)
+	if err != nil || !applied {
+		return applied, err
+	}
+
+	err = srtd.sortedSetClient.unmarkAll(ctx, param, unmarks)
+	if err != nil || !admit {
+		return true, err
+	}
+
+	err = srtd.sortedSetClient.track(ctx, param, item.GetRandId())
+	if err != nil {
+		return true, err
+	}
+	return true, srtd.trimSortedSet(ctx, param)
+}
+
+// zWrite describes adding item to the sorted set of param, or with xx only
+// re-scoring it when it is already a member.
+func (cr *SortedSet[T]) zWrite(param []string, score float64, item T, xx bool) (ZWrite, error) {
+	key, err := cr.key(param)
+	if err != nil {
+		return ZWrite{}, err
+	}
+
+	return ZWrite{
+		Key:    key,
+		Member: ZMember{Score: score, Member: item.GetRandId()},
+		TTL:    SORTED_SET_TTL,
+		XX:     xx,
+	}, nil
+}